@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+const ADJUST_SPLITS = "splits"
+const ADJUST_DIVIDENDS = "dividends"
+const ADJUST_TOTAL = "total"
+
+// T_PLUS_ONE_SINCE is the first day of T+1 settlement on MOEX, before it
+// shares were settled T+2 and went ex-dividend one day earlier.
+var T_PLUS_ONE_SINCE = time.Date(2023, 7, 31, 0, 0, 0, 0, time.UTC)
+
+type Split struct {
+	Date   time.Time `json:"date"`
+	Before float64   `json:"before"`
+	After  float64   `json:"after"`
+}
+
+type Dividend struct {
+	RecordDate time.Time `json:"record_date"`
+	Value      float64   `json:"value"`
+	Currency   string    `json:"currency"`
+}
+
+type CorporateActions struct {
+	Splits    []Split    `json:"splits"`
+	Dividends []Dividend `json:"dividends"`
+}
+
+func (actions CorporateActions) MarshalBinary() ([]byte, error) {
+	return json.Marshal(actions)
+}
+
+type CorporateActionsProvider interface {
+	GetCorporateActions(ctx context.Context, ticker string) (CorporateActions, error)
+}
+
+func ValidAdjust(adjust string) bool {
+	switch adjust {
+	case "", ADJUST_SPLITS, ADJUST_DIVIDENDS, ADJUST_TOTAL:
+		return true
+	}
+	return false
+}
+
+// Adjust back-adjusts prices before every split and dividend so that the
+// latest prices stay as traded. Dividends in another currency than
+// currency are skipped.
+func Adjust(entries HistoryEntries, actions CorporateActions, mode string, currency string) HistoryEntries {
+	if mode == "" || len(entries) == 0 {
+		return entries
+	}
+
+	adjusted := make(HistoryEntries, len(entries))
+	copy(adjusted, entries)
+	sort.SliceStable(adjusted, func(i, j int) bool {
+		return adjusted[i].Date.Before(adjusted[j].Date)
+	})
+	// dividend yields are computed from traded prices
+	raw := make(HistoryEntries, len(adjusted))
+	copy(raw, adjusted)
+
+	// first index trading on or after date
+	indexOf := func(date time.Time) int {
+		return sort.Search(len(adjusted), func(i int) bool {
+			return !adjusted[i].Date.Before(date)
+		})
+	}
+
+	if mode == ADJUST_SPLITS || mode == ADJUST_TOTAL {
+		for _, split := range actions.Splits {
+			if split.Before == 0 || split.After == 0 {
+				continue
+			}
+			factor := split.Before / split.After
+			for i := 0; i < indexOf(split.Date); i++ {
+				scalePrices(&adjusted[i], factor)
+				adjusted[i].Volume = uint64(float64(adjusted[i].Volume) / factor)
+			}
+		}
+	}
+
+	if mode == ADJUST_DIVIDENDS || mode == ADJUST_TOTAL {
+		for _, dividend := range actions.Dividends {
+			if dividend.Currency != currency {
+				continue
+			}
+
+			// last day the share was bought with the dividend
+			cum := indexOf(dividend.RecordDate) - 1
+			if dividend.RecordDate.Before(T_PLUS_ONE_SINCE) {
+				cum--
+			}
+			if cum < 0 || cum >= len(raw)-1 || raw[cum].Close == 0 {
+				continue
+			}
+
+			factor := 1 - dividend.Value/raw[cum].Close
+			if factor <= 0 {
+				continue
+			}
+			for i := 0; i <= cum; i++ {
+				scalePrices(&adjusted[i], factor)
+			}
+		}
+	}
+
+	return adjusted
+}
+
+func scalePrices(entry *HistoryEntry, factor float64) {
+	entry.Open *= factor
+	entry.Close *= factor
+	entry.High *= factor
+	entry.Low *= factor
+}
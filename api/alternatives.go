@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+// Source is a ticker of a provider.
+type Source struct {
+	Provider string
+	Ticker   string
+}
+
+// ALTERNATIVE_SOURCES lists by provider and ticker the sources with the same
+// prices tried in order when the provider fails to return a ticker.
+var ALTERNATIVE_SOURCES = map[string]map[string][]Source{
+	"moex": cbrfAlternatives(),
+}
+
+// cbrfAlternatives falls back from the ISS cbrf_* tickers to CBR itself.
+func cbrfAlternatives() map[string][]Source {
+	alternatives := map[string][]Source{}
+	for currency := range CBR_CURRENCIES {
+		alternatives["cbrf_"+currency] = []Source{{Provider: "cbr", Ticker: currency}}
+	}
+	return alternatives
+}
+
+// isUpstreamError tells whether err means the provider could not be reached
+// or answered badly. An unknown ticker, a rejected URL or a request cancelled
+// by the client is not a reason to ask another provider.
+func isUpstreamError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch err {
+	case custom_errors.ErrorCouldNotFetchData, custom_errors.ErrorCouldNotParseJSON, custom_errors.ErrorNoData:
+		return true
+	}
+	// http.Client returns transport errors as *url.Error
+	var transportErr *url.Error
+	return errors.As(err, &transportErr)
+}
+
+// WithAlternatives calls get for requested and, when it fails with an
+// upstream error, for its ALTERNATIVE_SOURCES in order. Alternatives get
+// options without the board overrides, which only apply to requested. It
+// returns the source that answered or the error of requested.
+func WithAlternatives[T any](ctx context.Context, providers map[string]Provider, requested Source,
+	options Options, get func(Provider, Source, Options) (T, error)) (T, Source, error) {
+
+	var data T
+	provider, ok := providers[requested.Provider]
+	if !ok {
+		return data, requested, custom_errors.ErrorNotFound
+	}
+	data, err := get(provider, requested, options)
+	if err == nil || !isUpstreamError(ctx, err) {
+		return data, requested, err
+	}
+
+	alternativeOptions := options
+	alternativeOptions.Board, alternativeOptions.Market, alternativeOptions.Engine = "", "", ""
+	for _, alternative := range ALTERNATIVE_SOURCES[requested.Provider][requested.Ticker] {
+		provider, ok := providers[alternative.Provider]
+		if !ok {
+			continue
+		}
+		log.Printf("Trying %s %s after %s %s failed: %v\n",
+			alternative.Provider, alternative.Ticker, requested.Provider, requested.Ticker, err)
+		alternativeData, alternativeErr := get(provider, alternative, alternativeOptions)
+		if alternativeErr == nil {
+			return alternativeData, alternative, nil
+		}
+		log.Println(alternativeErr)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return data, requested, err
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+type stubProvider struct {
+	data HistoryEntries
+	err  error
+}
+
+func (provider stubProvider) GetTicker(ctx context.Context, ticker string, options Options) (HistoryEntries, error) {
+	return provider.data, provider.err
+}
+
+func (provider stubProvider) GetMetadata(ctx context.Context, ticker string, options Options) (Metadata, error) {
+	return Metadata{}, provider.err
+}
+
+func getStubTicker(provider Provider, source Source, options Options) (HistoryEntries, error) {
+	if options.Board != "" && source.Provider != "moex" {
+		return HistoryEntries{}, custom_errors.ErrorNotFound
+	}
+	return provider.GetTicker(context.Background(), source.Ticker, options)
+}
+
+func TestWithAlternatives(t *testing.T) {
+	history := HistoryEntries{{Date: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), Close: 90.37}}
+	requested := Source{Provider: "moex", Ticker: "cbrf_usd"}
+	options := Options{Board: "cett"}
+
+	providers := map[string]Provider{
+		"moex": stubProvider{err: custom_errors.ErrorCouldNotFetchData},
+		"cbr":  stubProvider{data: history},
+	}
+	data, source, err := WithAlternatives(context.Background(), providers, requested, options, getStubTicker)
+	if err != nil || len(data) != 1 || source != (Source{Provider: "cbr", Ticker: "usd"}) {
+		t.Errorf("got %v from %v, %v, want the CBR history", data, source, err)
+	}
+
+	providers["moex"] = stubProvider{err: custom_errors.ErrorNotFound}
+	_, source, err = WithAlternatives(context.Background(), providers, requested, options, getStubTicker)
+	if err != custom_errors.ErrorNotFound || source != requested {
+		t.Errorf("got %v from %v for an unknown ticker, want no fallback", err, source)
+	}
+
+	providers["moex"] = stubProvider{err: context.Canceled}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, source, err = WithAlternatives(ctx, providers, requested, options, getStubTicker)
+	if err != context.Canceled || source != requested {
+		t.Errorf("got %v from %v for a cancelled request, want no fallback", err, source)
+	}
+
+	providers["moex"] = stubProvider{err: custom_errors.ErrorCouldNotFetchData}
+	providers["cbr"] = stubProvider{err: custom_errors.ErrorCouldNotFetchData}
+	_, source, err = WithAlternatives(context.Background(), providers, requested, options, getStubTicker)
+	if err != custom_errors.ErrorCouldNotFetchData || source != requested {
+		t.Errorf("got %v from %v, want the original error", err, source)
+	}
+}
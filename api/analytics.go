@@ -0,0 +1,258 @@
+package api
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const TRADING_DAYS_PER_YEAR = 252
+
+type Returns struct {
+	Day   *float64 `json:"1d"`
+	Month *float64 `json:"1m"`
+	YTD   *float64 `json:"ytd"`
+	Year  *float64 `json:"1y"`
+	CAGR3 *float64 `json:"3y_cagr"`
+}
+
+type SeriesPoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+}
+
+type Analytics struct {
+	Returns     Returns                  `json:"returns"`
+	Volatility  *float64                 `json:"volatility"`
+	MaxDrawdown float64                  `json:"max_drawdown"`
+	SMA         map[string][]SeriesPoint `json:"sma,omitempty"`
+	EMA         map[string][]SeriesPoint `json:"ema,omitempty"`
+	Correlation *float64                 `json:"correlation,omitempty"`
+}
+
+// ValidAnalyticsOptions reports whether options give one bar per trading
+// day. Volatility is annualized with TRADING_DAYS_PER_YEAR and the daily
+// return compares adjacent bars, so other bars give wrong numbers.
+func ValidAnalyticsOptions(options Options) bool {
+	return isDaily(options.Interval) && options.Period == "" && options.Fill == ""
+}
+
+// closes returns entries with a close price sorted by date.
+func closes(entries HistoryEntries) HistoryEntries {
+	output := make(HistoryEntries, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Close != 0 {
+			output = append(output, entry)
+		}
+	}
+	sort.SliceStable(output, func(i, j int) bool {
+		return output[i].Date.Before(output[j].Date)
+	})
+	return output
+}
+
+// ComputeAnalytics computes returns, annualized volatility of the last year of
+// daily log returns, maximum drawdown and moving averages of close prices.
+func ComputeAnalytics(entries HistoryEntries, sma []int, ema []int) Analytics {
+	series := closes(entries)
+
+	var analytics Analytics
+	if len(series) == 0 {
+		return analytics
+	}
+
+	last := series[len(series)-1]
+	if len(series) > 1 {
+		analytics.Returns.Day = ratio(last.Close, series[len(series)-2].Close)
+	}
+	analytics.Returns.Month = periodReturn(series, last.Date.AddDate(0, -1, 0))
+	analytics.Returns.Year = periodReturn(series, last.Date.AddDate(-1, 0, 0))
+	analytics.Returns.YTD = periodReturn(series,
+		time.Date(last.Date.Year(), time.January, 1, 0, 0, 0, 0, last.Date.Location()).Add(-time.Nanosecond))
+	if total := periodReturn(series, last.Date.AddDate(-3, 0, 0)); total != nil {
+		cagr := math.Pow(1+*total, 1.0/3) - 1
+		analytics.Returns.CAGR3 = &cagr
+	}
+
+	analytics.Volatility = volatility(logReturns(series))
+	analytics.MaxDrawdown = maxDrawdown(series)
+
+	if len(sma) > 0 {
+		analytics.SMA = map[string][]SeriesPoint{}
+		for _, window := range sma {
+			analytics.SMA[strconv.Itoa(window)] = simpleMovingAverage(series, window)
+		}
+	}
+	if len(ema) > 0 {
+		analytics.EMA = map[string][]SeriesPoint{}
+		for _, window := range ema {
+			analytics.EMA[strconv.Itoa(window)] = exponentialMovingAverage(series, window)
+		}
+	}
+
+	return analytics
+}
+
+func ratio(current float64, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	value := current/previous - 1
+	return &value
+}
+
+// periodReturn returns the change of the last close against the last close
+// on or before date, nil when the series does not reach that far.
+func periodReturn(series HistoryEntries, date time.Time) *float64 {
+	i := sort.Search(len(series), func(i int) bool {
+		return series[i].Date.After(date)
+	})
+	if i == 0 {
+		return nil
+	}
+	return ratio(series[len(series)-1].Close, series[i-1].Close)
+}
+
+func logReturns(series HistoryEntries) []float64 {
+	if len(series) > TRADING_DAYS_PER_YEAR+1 {
+		series = series[len(series)-TRADING_DAYS_PER_YEAR-1:]
+	}
+	returns := make([]float64, 0, len(series))
+	for i := 1; i < len(series); i++ {
+		returns = append(returns, math.Log(series[i].Close/series[i-1].Close))
+	}
+	return returns
+}
+
+func volatility(returns []float64) *float64 {
+	if len(returns) < 2 {
+		return nil
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	value := math.Sqrt(variance * TRADING_DAYS_PER_YEAR)
+	return &value
+}
+
+// maxDrawdown returns the largest peak to trough decline as a negative fraction.
+func maxDrawdown(series HistoryEntries) float64 {
+	peak := 0.0
+	drawdown := 0.0
+	for _, entry := range series {
+		if entry.Close > peak {
+			peak = entry.Close
+		}
+		if value := entry.Close/peak - 1; value < drawdown {
+			drawdown = value
+		}
+	}
+	return drawdown
+}
+
+func simpleMovingAverage(series HistoryEntries, window int) []SeriesPoint {
+	if window < 1 || len(series) < window {
+		return []SeriesPoint{}
+	}
+	points := make([]SeriesPoint, 0, len(series)-window+1)
+	sum := 0.0
+	for i, entry := range series {
+		sum += entry.Close
+		if i >= window {
+			sum -= series[i-window].Close
+		}
+		if i >= window-1 {
+			points = append(points, SeriesPoint{Date: entry.Date, Value: sum / float64(window)})
+		}
+	}
+	return points
+}
+
+// exponentialMovingAverage is seeded with the simple average of the first window closes.
+func exponentialMovingAverage(series HistoryEntries, window int) []SeriesPoint {
+	if window < 1 || len(series) < window {
+		return []SeriesPoint{}
+	}
+	alpha := 2.0 / float64(window+1)
+	points := make([]SeriesPoint, 0, len(series)-window+1)
+
+	value := 0.0
+	for _, entry := range series[:window] {
+		value += entry.Close
+	}
+	value /= float64(window)
+	points = append(points, SeriesPoint{Date: series[window-1].Date, Value: value})
+
+	for _, entry := range series[window:] {
+		value = alpha*entry.Close + (1-alpha)*value
+		points = append(points, SeriesPoint{Date: entry.Date, Value: value})
+	}
+	return points
+}
+
+// Correlation returns the correlation of daily returns of entries and benchmark
+// over the last year of dates both series traded on.
+func Correlation(entries HistoryEntries, benchmark HistoryEntries) *float64 {
+	benchmarkCloses := map[time.Time]float64{}
+	for _, entry := range closes(benchmark) {
+		benchmarkCloses[dateOf(entry.Date)] = entry.Close
+	}
+
+	var common HistoryEntries
+	var commonBenchmark []float64
+	for _, entry := range closes(entries) {
+		if value, ok := benchmarkCloses[dateOf(entry.Date)]; ok {
+			common = append(common, entry)
+			commonBenchmark = append(commonBenchmark, value)
+		}
+	}
+	if len(common) > TRADING_DAYS_PER_YEAR+1 {
+		common = common[len(common)-TRADING_DAYS_PER_YEAR-1:]
+		commonBenchmark = commonBenchmark[len(commonBenchmark)-TRADING_DAYS_PER_YEAR-1:]
+	}
+	if len(common) < 3 {
+		return nil
+	}
+
+	var x, y []float64
+	for i := 1; i < len(common); i++ {
+		x = append(x, common[i].Close/common[i-1].Close-1)
+		y = append(y, commonBenchmark[i]/commonBenchmark[i-1]-1)
+	}
+
+	meanX, meanY := 0.0, 0.0
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+
+	covariance, varianceX, varianceY := 0.0, 0.0, 0.0
+	for i := range x {
+		covariance += (x[i] - meanX) * (y[i] - meanY)
+		varianceX += (x[i] - meanX) * (x[i] - meanX)
+		varianceY += (y[i] - meanY) * (y[i] - meanY)
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return nil
+	}
+
+	value := covariance / math.Sqrt(varianceX*varianceY)
+	return &value
+}
+
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+type BatchItem struct {
+	Provider string `json:"provider"`
+	Ticker   string `json:"ticker"`
+}
+
+type BatchResult struct {
+	Item BatchItem
+	// Source is the ticker that answered, an alternative when Item failed
+	Source        Source
+	Data          HistoryEntries
+	Normalization NormalizationReport
+	Error         error
+}
+
+// FetchBatch fetches every item with at most concurrency parallel requests.
+// Results are returned in the same order as items, currency rates are
+// fetched once for all of them.
+func FetchBatch(ctx context.Context, providers map[string]Provider, pipeline *Pipeline,
+	items []BatchItem, options Options, concurrency int) []BatchResult {
+
+	ctx = WithRateCache(ctx)
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]BatchResult, len(items))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		results[i].Item = item
+
+		if _, ok := providers[item.Provider]; !ok {
+			results[i].Error = custom_errors.ErrorNotFound
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, item BatchItem) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results[i].Error = ctx.Err()
+				return
+			}
+			defer func() { <-slots }()
+
+			type history struct {
+				data   HistoryEntries
+				report NormalizationReport
+			}
+			output, source, err := WithAlternatives(ctx, providers, Source{Provider: item.Provider, Ticker: item.Ticker}, options,
+				func(provider Provider, source Source, options Options) (history, error) {
+					data, report, err := pipeline.GetHistory(ctx, provider, source.Ticker, options)
+					return history{data, report}, err
+				})
+			results[i].Data, results[i].Normalization, results[i].Source, results[i].Error = output.data, output.report, source, err
+		}(i, item)
+	}
+
+	wg.Wait()
+	return results
+}
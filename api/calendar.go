@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type TradingCalendar struct {
+	WorkDays map[time.Weekday]bool `json:"work_days"`
+	// Exceptions override WorkDays for single YYYY-MM-DD dates
+	Exceptions map[string]bool `json:"exceptions"`
+}
+
+func (calendar TradingCalendar) MarshalBinary() ([]byte, error) {
+	return json.Marshal(calendar)
+}
+
+// DefaultCalendar trades Monday to Friday, it is used when the exchange
+// does not publish its own calendar.
+func DefaultCalendar() TradingCalendar {
+	return TradingCalendar{
+		WorkDays: map[time.Weekday]bool{
+			time.Monday:    true,
+			time.Tuesday:   true,
+			time.Wednesday: true,
+			time.Thursday:  true,
+			time.Friday:    true,
+		},
+		Exceptions: map[string]bool{},
+	}
+}
+
+func (calendar TradingCalendar) IsTradingDay(date time.Time) bool {
+	if isWorkDay, ok := calendar.Exceptions[date.Format("2006-01-02")]; ok {
+		return isWorkDay
+	}
+	return calendar.WorkDays[date.Weekday()]
+}
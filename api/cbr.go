@@ -1,11 +1,13 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -16,20 +18,41 @@ import (
 	"golang.org/x/text/encoding/charmap"
 )
 
+const CBR_SOURCE = "Bank of Russia"
+
+// CBR_CACHE_TTL is how long rate history is cached. CBR publishes the next
+// day rate in the afternoon, so it is not cached until the end of the day.
+const CBR_CACHE_TTL = time.Hour
+
+// CBR_USER_AGENT is the default for CBR requests, www.cbr.ru rejects
+// clients it does not recognize as browsers.
+const CBR_USER_AGENT = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+
 // https://www.cbr.ru/scripts/XML_val.asp?d=0
 var CBR_CURRENCIES = map[string]string{
 	"usd": "R01235",
 	"cny": "R01375",
 	"eur": "R01239",
+	"gbp": "R01035",
+	"chf": "R01775",
+	"jpy": "R01820",
+	"hkd": "R01200",
+	"kzt": "R01335",
 }
 
 type CbrAPI struct {
 	BaseURL string
+	Fetcher *utils.Fetcher
+	Redis   utils.RedisClient
 }
 
-func NewCbrAPI() CbrAPI {
+func NewCbrAPI(redis utils.RedisClient) CbrAPI {
+	fetcher := *utils.DefaultFetcher
+	fetcher.UserAgent = CBR_USER_AGENT
 	return CbrAPI{
 		BaseURL: constants.CbrBaseApiURL,
+		Fetcher: &fetcher,
+		Redis:   redis,
 	}
 }
 
@@ -45,41 +68,90 @@ type Record struct {
 	VunitRate string `xml:"VunitRate"`
 }
 
-func (api *CbrAPI) GetTicker(ticker string) (HistoryEntries, error) {
+// GetTicker returns the whole rate history of ticker. It is cached, as
+// currency conversion needs it for every converted request.
+func (api *CbrAPI) GetTicker(ctx context.Context, ticker string, options Options) (HistoryEntries, error) {
+	cacheKey := fmt.Sprintf("cbr-%s", ticker)
+
+	if api.Redis.Client != nil {
+		log.Printf("Getting rates from cache for %s\n", cacheKey)
+		data, err := api.Redis.Client.Get(api.Redis.Context, cacheKey).Bytes()
+		if err == nil {
+			var history HistoryEntries
+			err = json.Unmarshal(data, &history)
+			if err == nil {
+				return history, nil
+			}
+		}
+		log.Printf("Got no rates from cache for %s\n", cacheKey)
+	}
+
+	endDate := time.Now().In(utils.MoscowLocation)
+	startDate := time.Date(2014, 01, 01, 01, 01, 01, 01, time.UTC)
+	history, err := api.getTickerRange(ctx, ticker, startDate, endDate)
+	if err != nil {
+		return HistoryEntries{}, err
+	}
+
+	if api.Redis.Client != nil {
+		log.Printf("Saving rates to cache for %s for %d seconds\n", cacheKey, uint64(CBR_CACHE_TTL.Seconds()))
+		err = api.Redis.Client.Set(api.Redis.Context, cacheKey, history, CBR_CACHE_TTL).Err()
+		if err != nil {
+			return HistoryEntries{}, err
+		}
+	}
+
+	return history, nil
+}
+
+func (api *CbrAPI) GetMetadata(ctx context.Context, ticker string, options Options) (Metadata, error) {
+	if !utils.Contains(CBR_CURRENCIES, ticker) {
+		return Metadata{}, custom_errors.ErrorNotFound
+	}
+	return Metadata{
+		Currency:  CURRENCY_RUB,
+		Name:      strings.ToUpper(ticker),
+		Source:    CBR_SOURCE,
+		SourceURL: api.BaseURL,
+	}, nil
+}
+
+func (api *CbrAPI) GetQuote(ctx context.Context, ticker string, options Options) (Quote, error) {
+	endDate := time.Now().In(utils.MoscowLocation)
+	startDate := endDate.AddDate(0, 0, -QUOTE_LOOKBACK_DAYS)
+
+	history, err := api.getTickerRange(ctx, ticker, startDate, endDate)
+	if err != nil {
+		return Quote{}, err
+	}
+	return QuoteFromHistory(history)
+}
+
+func (api *CbrAPI) getTickerRange(ctx context.Context, ticker string,
+	startDate time.Time, endDate time.Time) (HistoryEntries, error) {
 
 	if !utils.Contains(CBR_CURRENCIES, ticker) {
 		return HistoryEntries{}, custom_errors.ErrorNotFound
 	}
 
-	endDate := time.Now()
-	startDate := time.Date(2014, 01, 01, 01, 01, 01, 01, time.UTC)
 	dateFormat := "02/01/2006"
 
 	url := fmt.Sprintf(
-		"https://www.cbr.ru/scripts/XML_dynamic.asp?date_req1=%s&date_req2=%s&VAL_NM_RQ=%s",
+		"%s/scripts/XML_dynamic.asp?date_req1=%s&date_req2=%s&VAL_NM_RQ=%s",
+		api.BaseURL,
 		startDate.Format(dateFormat),
 		endDate.Format(dateFormat),
 		CBR_CURRENCIES[ticker],
 	)
 	log.Printf("Getting data from %s\n", url)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Printf("Error creating request: %v\n", err)
-		return HistoryEntries{}, err
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	data, err := api.Fetcher.Get(ctx, url)
 	if err != nil {
-		log.Printf("Error making request: %v\n", err)
+		log.Printf("Error getting data: %v\n", err)
 		return HistoryEntries{}, err
 	}
-	defer resp.Body.Close()
 
-	d := xml.NewDecoder(resp.Body)
+	d := xml.NewDecoder(bytes.NewReader(data))
 	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
 		switch charset {
 		case "windows-1251":
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+func TestCbrGetTicker(t *testing.T) {
+	server := newFakeServer(t)
+	cbr := server.cbrAPI()
+
+	entries, err := cbr.GetTicker(context.Background(), "usd", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := server.requestCount("/cbr/scripts/XML_dynamic.asp"); got != 1 {
+		t.Errorf("requested CBR %d times, want 1", got)
+	}
+
+	// the windows-1251 record without a rate is kept empty
+	want := []struct {
+		date  string
+		close float64
+	}{
+		{"2024-01-10", 90.37},
+		{"2024-01-11", 89.6883},
+		{"2024-01-12", 0},
+		{"2024-01-13", 88.771},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if !entry.Date.Equal(date(want[i].date)) || entry.Close != want[i].close {
+			t.Errorf("entry %d is %s %v, want %s %v", i, entry.Date, entry.Close, want[i].date, want[i].close)
+		}
+	}
+
+	normalized, report := Normalize(entries)
+	if report.Dropped != 1 || len(normalized) != 3 {
+		t.Errorf("normalization dropped %d rows, want 1", report.Dropped)
+	}
+}
+
+func TestCbrUnknownCurrency(t *testing.T) {
+	server := newFakeServer(t)
+	cbr := server.cbrAPI()
+
+	_, err := cbr.GetTicker(context.Background(), "xyz", Options{})
+	if err != custom_errors.ErrorNotFound {
+		t.Errorf("got %v, want %v", err, custom_errors.ErrorNotFound)
+	}
+	if got := server.requestCount("/cbr/scripts/XML_dynamic.asp"); got != 0 {
+		t.Errorf("requested CBR %d times for an unknown currency", got)
+	}
+}
+
+func TestCbrConvertBonds(t *testing.T) {
+	server := newFakeServer(t)
+	cbr := server.cbrAPI()
+
+	entries := HistoryEntries{{Date: date("2024-01-11"), Close: 98.5, High: 99, Low: 98, Facevalue: 1000}}
+	converted, _, err := cbr.Convert(context.Background(), entries, CURRENCY_RUB, "usd", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(converted) != 1 || converted[0].Close != 98.5 || converted[0].High != 99 {
+		t.Errorf("got %+v, want prices in percent kept", converted)
+	}
+	if facevalue := converted[0].Facevalue; facevalue < 11.14 || facevalue > 11.15 {
+		t.Errorf("facevalue is %v, want 1000 roubles in dollars", facevalue)
+	}
+}
+
+func TestCbrConvertBeforeFirstRate(t *testing.T) {
+	server := newFakeServer(t)
+	cbr := server.cbrAPI()
+
+	entries := HistoryEntries{
+		{Date: date("2024-01-09"), Close: 100},
+		{Date: date("2024-01-11"), Close: 100},
+	}
+	converted, unconverted, err := cbr.Convert(context.Background(), entries, "usd", CURRENCY_RUB, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(converted) != 1 || unconverted != 1 {
+		t.Errorf("got %d rows and %d unconverted, want the row before the first rate counted", len(converted), unconverted)
+	}
+}
+
+func TestCbrConvertUnsupportedCurrency(t *testing.T) {
+	server := newFakeServer(t)
+	cbr := server.cbrAPI()
+
+	entries := HistoryEntries{{Date: date("2024-01-11"), Close: 100}}
+	_, _, err := cbr.Convert(context.Background(), entries, "xau", "usd", false)
+	if err != custom_errors.ErrorNotAllowed {
+		t.Errorf("got %v for a currency CBR does not publish, want %v", err, custom_errors.ErrorNotAllowed)
+	}
+}
+
+func TestCbrConvertCache(t *testing.T) {
+	server := newFakeServer(t)
+	cbr := server.cbrAPI()
+	cbr.Redis = newFakeRedis(t)
+
+	entries := HistoryEntries{{Date: date("2024-01-11"), Close: 100}}
+	for range 3 {
+		if _, _, err := cbr.Convert(context.Background(), entries, "usd", CURRENCY_RUB, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.requestCount("/cbr/scripts/XML_dynamic.asp"); got != 1 {
+		t.Errorf("requested CBR %d times, want the rates cached", got)
+	}
+}
+
+func TestCbrConvertSharedRates(t *testing.T) {
+	server := newFakeServer(t)
+	cbr := server.cbrAPI()
+
+	ctx := WithRateCache(context.Background())
+	entries := HistoryEntries{{Date: date("2024-01-11"), Close: 100}}
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := cbr.Convert(ctx, entries, CURRENCY_RUB, "usd", false); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := server.requestCount("/cbr/scripts/XML_dynamic.asp"); got != 1 {
+		t.Errorf("requested CBR %d times, want the rates shared by the request", got)
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+const CURRENCY_RUB = "rub"
+
+type rateCacheKey struct{}
+
+// rateCache keeps the rates fetched for one request, so the tickers of a
+// batch fetch the rates of a currency once even without Redis.
+type rateCache struct {
+	mutex sync.Mutex
+	rates map[string]*cachedRates
+}
+
+type cachedRates struct {
+	once  sync.Once
+	rates HistoryEntries
+	err   error
+}
+
+// WithRateCache returns ctx whose conversions share the fetched rates.
+func WithRateCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rateCacheKey{}, &rateCache{rates: map[string]*cachedRates{}})
+}
+
+func ValidCurrency(currency string) bool {
+	return currency == "" || currency == CURRENCY_RUB || utils.Contains(CBR_CURRENCIES, currency)
+}
+
+// Convert converts prices of entries from one currency to another using
+// CBR rates. Rates are forward-filled over weekends and holidays, entries
+// older than the first known rate are dropped and counted. Prices quoted
+// in percent of face value, such as MOEX bond prices, are kept and only
+// the face value is converted.
+func (api *CbrAPI) Convert(ctx context.Context, entries HistoryEntries,
+	from string, to string, percentOfFace bool) (HistoryEntries, int, error) {
+
+	from = strings.ToLower(from)
+	to = strings.ToLower(to)
+	if from == to {
+		return entries, 0, nil
+	}
+
+	fromRates, err := api.getRates(ctx, from)
+	if err != nil {
+		return HistoryEntries{}, 0, err
+	}
+	toRates, err := api.getRates(ctx, to)
+	if err != nil {
+		return HistoryEntries{}, 0, err
+	}
+
+	converted := make(HistoryEntries, 0, len(entries))
+	for _, entry := range entries {
+		fromRate, ok := rateAt(fromRates, entry)
+		if !ok {
+			continue
+		}
+		toRate, ok := rateAt(toRates, entry)
+		if !ok || toRate == 0 {
+			continue
+		}
+
+		factor := fromRate / toRate
+		if !percentOfFace {
+			entry.Open *= factor
+			entry.Close *= factor
+			entry.High *= factor
+			entry.Low *= factor
+		}
+		entry.Facevalue *= factor
+		converted = append(converted, entry)
+	}
+
+	return converted, len(entries) - len(converted), nil
+}
+
+// getRates returns roubles per unit of currency, nil rates mean roubles.
+// A currency CBR does not publish is not allowed, the security itself may
+// exist.
+func (api *CbrAPI) getRates(ctx context.Context, currency string) (HistoryEntries, error) {
+	if currency == CURRENCY_RUB {
+		return nil, nil
+	}
+	if !utils.Contains(CBR_CURRENCIES, currency) {
+		return HistoryEntries{}, custom_errors.ErrorNotAllowed
+	}
+
+	cache, ok := ctx.Value(rateCacheKey{}).(*rateCache)
+	if !ok {
+		return api.fetchRates(ctx, currency)
+	}
+	cache.mutex.Lock()
+	cached, found := cache.rates[currency]
+	if !found {
+		cached = &cachedRates{}
+		cache.rates[currency] = cached
+	}
+	cache.mutex.Unlock()
+
+	cached.once.Do(func() {
+		cached.rates, cached.err = api.fetchRates(ctx, currency)
+	})
+	return cached.rates, cached.err
+}
+
+// fetchRates returns the rates of currency sorted by date.
+func (api *CbrAPI) fetchRates(ctx context.Context, currency string) (HistoryEntries, error) {
+	rates, err := api.GetTicker(ctx, currency, Options{})
+	if err != nil {
+		return HistoryEntries{}, err
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Date.Before(rates[j].Date)
+	})
+	return rates, nil
+}
+
+// rateAt returns the latest rate published on or before the entry date.
+func rateAt(rates HistoryEntries, entry HistoryEntry) (float64, bool) {
+	if rates == nil {
+		return 1, true
+	}
+
+	i := sort.Search(len(rates), func(i int) bool {
+		return rates[i].Date.After(entry.Date)
+	})
+	for i--; i >= 0; i-- {
+		if rates[i].Close != 0 {
+			return rates[i].Close, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeServer serves ISS, investcab and CBR responses recorded in testdata.
+// ISS is served at the root, investcab under /investcab and CBR under /cbr.
+type fakeServer struct {
+	*httptest.Server
+	t *testing.T
+
+	mutex    sync.Mutex
+	requests map[string]int
+	statuses map[string]int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	server := &fakeServer{
+		t:        t,
+		requests: map[string]int{},
+		statuses: map[string]int{},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// fixturePath maps a request to its file in testdata. Query parameters that
+// select data are part of the name, the others are ignored.
+func fixturePath(r *http.Request) string {
+	query := r.URL.Query()
+	name := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/"))
+
+	switch {
+	case strings.HasPrefix(name, "investcab/chistory"):
+		name += "_" + strings.ToLower(query.Get("symbol")) + "_" + strings.ToLower(query.Get("resolution")) + ".json"
+	case strings.HasPrefix(name, "cbr/scripts/xml_dynamic.asp"):
+		name = "cbr/xml_dynamic_" + strings.ToLower(query.Get("VAL_NM_RQ")) + ".xml"
+	default:
+		if start := query.Get("start"); start != "" && start != "0" {
+			name = strings.TrimSuffix(name, ".json") + "_" + start + ".json"
+		}
+	}
+
+	return filepath.Join("testdata", filepath.FromSlash(name))
+}
+
+func (server *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	server.requests[r.URL.Path]++
+	status, failing := server.statuses[r.URL.Path]
+	server.mutex.Unlock()
+
+	if failing {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	data, err := os.ReadFile(fixturePath(r))
+	if err != nil {
+		server.t.Logf("no fixture for %s: %v", r.URL, err)
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(data)
+}
+
+// fail makes every request to path answer with status.
+func (server *fakeServer) fail(path string, status int) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.statuses[path] = status
+}
+
+func (server *fakeServer) requestCount(path string) int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.requests[path]
+}
+
+func (server *fakeServer) fetcher() *utils.Fetcher {
+	return utils.NewFetcher(server.Client(), server.URL)
+}
+
+func (server *fakeServer) cbrAPI() *CbrAPI {
+	cbr := NewCbrAPI(utils.RedisClient{})
+	cbr.BaseURL = server.URL + "/cbr"
+	cbr.Fetcher = server.fetcher()
+	return &cbr
+}
+
+func (server *fakeServer) moexAPI(redis utils.RedisClient) *MoexAPI {
+	moex := NewMoexAPI(redis, server.cbrAPI())
+	moex.BaseURL = server.URL
+	moex.Fetcher = server.fetcher()
+	return &moex
+}
+
+func (server *fakeServer) spbexAPI() *SpbexAPI {
+	spbex := NewSpbexAPI()
+	spbex.BaseURL = server.URL + "/investcab"
+	spbex.Fetcher = server.fetcher()
+	return &spbex
+}
+
+func newFakeRedis(t *testing.T) utils.RedisClient {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return utils.RedisClient{
+		Client:  client,
+		Context: context.Background(),
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import "sort"
+
+const FILL_FFILL = "ffill"
+
+func ValidFill(fill string) bool {
+	return fill == "" || fill == FILL_FFILL
+}
+
+// ForwardFill emits one row per calendar day between the first and the last
+// entry. Missing days repeat the previous close with zero volume.
+func ForwardFill(entries HistoryEntries) HistoryEntries {
+	if len(entries) == 0 {
+		return entries
+	}
+
+	sorted := make(HistoryEntries, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	filled := HistoryEntries{sorted[0]}
+	for _, entry := range sorted[1:] {
+		previous := filled[len(filled)-1]
+		for day := dateOf(previous.Date).AddDate(0, 0, 1); day.Before(dateOf(entry.Date)); day = day.AddDate(0, 0, 1) {
+			filled = append(filled, HistoryEntry{
+				Date:      day,
+				Open:      previous.Close,
+				Close:     previous.Close,
+				High:      previous.Close,
+				Low:       previous.Close,
+				Facevalue: previous.Facevalue,
+			})
+		}
+		filled = append(filled, entry)
+	}
+
+	return filled
+}
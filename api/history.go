@@ -7,6 +7,7 @@ import (
 
 type HistoryEntry struct {
 	Date      time.Time `json:"date"`
+	Open      float64   `json:"open,omitempty"`
 	Close     float64   `json:"close"`
 	High      float64   `json:"high"`
 	Low       float64   `json:"low"`
@@ -0,0 +1,88 @@
+package api
+
+import "time"
+
+const INTERVAL_MINUTE = "1m"
+const INTERVAL_10_MINUTES = "10m"
+const INTERVAL_HOUR = "1h"
+const INTERVAL_DAY = "1d"
+const INTERVAL_WEEK = "1w"
+const INTERVAL_MONTH = "1M"
+
+type intervalSettings struct {
+	// MoexCandles is the ISS candles interval
+	MoexCandles int
+	// SpbexResolution is the investcab chart resolution
+	SpbexResolution string
+	// Lookback limits how far back intraday history goes, zero means all history
+	Lookback time.Duration
+	// CacheTTL is how long the last, still changing, page is cached
+	CacheTTL time.Duration
+	// Dated bars are dated by their first trading date like daily history,
+	// the others by the instant they begin
+	Dated bool
+}
+
+var INTERVALS = map[string]intervalSettings{
+	INTERVAL_MINUTE: {
+		MoexCandles:     1,
+		SpbexResolution: "1",
+		Lookback:        7 * 24 * time.Hour,
+		CacheTTL:        time.Minute,
+	},
+	INTERVAL_10_MINUTES: {
+		MoexCandles:     10,
+		SpbexResolution: "10",
+		Lookback:        30 * 24 * time.Hour,
+		CacheTTL:        10 * time.Minute,
+	},
+	INTERVAL_HOUR: {
+		MoexCandles:     60,
+		SpbexResolution: "60",
+		Lookback:        180 * 24 * time.Hour,
+		CacheTTL:        time.Hour,
+	},
+	INTERVAL_DAY: {
+		MoexCandles:     24,
+		SpbexResolution: "D",
+		Dated:           true,
+	},
+	INTERVAL_WEEK: {
+		MoexCandles:     7,
+		SpbexResolution: "W",
+		Dated:           true,
+	},
+	INTERVAL_MONTH: {
+		MoexCandles:     31,
+		SpbexResolution: "M",
+		Dated:           true,
+	},
+}
+
+func ValidInterval(interval string) bool {
+	if interval == "" {
+		return true
+	}
+	_, ok := INTERVALS[interval]
+	return ok
+}
+
+// isDaily reports whether interval is served by regular daily history.
+func isDaily(interval string) bool {
+	return interval == "" || interval == INTERVAL_DAY
+}
+
+// isDated reports whether bars of interval are trading dates.
+func isDated(interval string) bool {
+	return interval == "" || INTERVALS[interval].Dated
+}
+
+// intervalCacheTTL returns the cache duration of the last page for interval,
+// daily and longer bars change until the end of the day.
+func intervalCacheTTL(interval string) time.Duration {
+	settings := INTERVALS[interval]
+	if settings.CacheTTL == 0 {
+		return untilTomorrow()
+	}
+	return settings.CacheTTL
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,16 +14,25 @@ import (
 )
 
 const PAGE_SIZE = 100
+const MOEX_CLOSE_COLUMN = "CLOSE"
+const MOEX_SOURCE = "Moscow Exchange ISS"
+
+// MOEX_BONDS_MARKET prices are a percent of face value.
+const MOEX_BONDS_MARKET = "bonds"
 
 type MoexAPI struct {
 	BaseURL string
 	Redis   utils.RedisClient
+	Cbr     *CbrAPI
+	Fetcher *utils.Fetcher
 }
 
 type MoexSecurityParameters struct {
-	Board  string `json:"board"`
-	Market string `json:"market"`
-	Engine string `json:"engine"`
+	Board    string `json:"board"`
+	Market   string `json:"market"`
+	Engine   string `json:"engine"`
+	Currency string `json:"currency,omitempty"`
+	Name     string `json:"name,omitempty"`
 }
 
 func (params MoexSecurityParameters) MarshalBinary() ([]byte, error) {
@@ -34,6 +44,10 @@ type MoexSecurityParametersJSON struct {
 		Columns []string `json:"columns"`
 		Data    [][]any  `json:"data"`
 	} `json:"boards"`
+	Description struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"description"`
 }
 
 type MoexHistoryJSON struct {
@@ -61,24 +75,61 @@ type MoexCbrfPriceJSON struct {
 	} `json:"wap_rates"`
 }
 
-func NewMoexAPI(redis utils.RedisClient) MoexAPI {
+func NewMoexAPI(redis utils.RedisClient, cbr *CbrAPI) MoexAPI {
 	return MoexAPI{
 		BaseURL: constants.MoexBaseApiURL,
 		Redis:   redis,
+		Cbr:     cbr,
+		Fetcher: utils.DefaultFetcher,
 	}
 }
 
-func (api *MoexAPI) getRegularTicker(ticker string) (HistoryEntries, error) {
-	security, err := api.getSecurityParameters(ticker)
+func (api *MoexAPI) getRegularTicker(ctx context.Context, ticker string, options Options) (HistoryEntries, error) {
+	security, err := api.getSecurityParameters(ctx, ticker, options)
 	if err != nil {
 		log.Println(err)
 		return HistoryEntries{}, err
 	}
 
+	if !isDaily(options.Interval) {
+		return api.getCandles(ctx, ticker, security, options.Interval)
+	}
+
+	history, err := api.getSecurityHistory(ctx, ticker, security, MOEX_CLOSE_COLUMN)
+	if err != nil {
+		return HistoryEntries{}, err
+	}
+
+	calendar, err := api.GetCalendar(ctx, security.Engine)
+	if err != nil {
+		log.Printf("Could not get calendar for %s, assuming weekdays: %v\n", security.Engine, err)
+		calendar = DefaultCalendar()
+	}
+	if !calendar.IsTradingDay(utils.TradeDate(time.Now(), utils.MoscowLocation)) {
+		log.Printf("No trading session today for %s. Returning only history data.\n", ticker)
+		return history, nil
+	}
+
+	currentPrice, err := api.getSecurityCurrentPrice(ctx, ticker, security)
+	if err == nil {
+		history = mergeCurrentPrice(history, currentPrice)
+	}
+	// expired securities such as futures contracts have no market data at all
+	if err == custom_errors.ErrorNoData || (err == custom_errors.ErrorNotFound && len(history) > 0) {
+		log.Println("No current price data. Returning only history data.")
+		err = nil
+	}
+
+	return history, err
+}
+
+// getSecurityHistory fetches all history pages using closeColumn as the close price.
+func (api *MoexAPI) getSecurityHistory(ctx context.Context, ticker string,
+	security MoexSecurityParameters, closeColumn string) (HistoryEntries, error) {
 	var history HistoryEntries
 	offset := uint(0)
 	for {
-		entryHistory, err := api.getSecurityHistoryOffset(ticker, security, offset)
+		entryHistory, err := api.getSecurityHistoryOffset(ctx, ticker, security, closeColumn, offset)
 		if err != nil {
 			log.Println(err)
 			return HistoryEntries{}, err
@@ -95,75 +146,272 @@ func (api *MoexAPI) getRegularTicker(ticker string) (HistoryEntries, error) {
 		offset += PAGE_SIZE
 		history = append(history, entryHistory...)
 	}
+	return history, nil
+}
+
+// getCbrfTicker returns the CBR rate history for cbrf_* tickers. ISS only
+// publishes the latest CBR rates, it is appended when CBR has no such date yet.
+func (api *MoexAPI) getCbrfTicker(ctx context.Context, ticker string) (HistoryEntries, error) {
+	currency := strings.TrimPrefix(ticker, "cbrf_")
+
+	latest, latestErr := api.getCbrfLatest(ctx, currency)
+	if latestErr != nil {
+		log.Printf("No latest CBRF rate for %s: %v\n", ticker, latestErr)
+	}
 
-	currentPrice, err := api.getSecurityCurrentPrice(ticker, security)
-	if err == nil {
-		history = append(history, currentPrice)
-		if len(history) > 1 {
-			history[len(history)-1].Facevalue = history[len(history)-2].Facevalue
+	if api.Cbr != nil {
+		history, err := api.Cbr.GetTicker(ctx, currency, Options{})
+		if err == nil && len(history) > 0 {
+			if latestErr == nil && latest.Date.After(history[len(history)-1].Date) {
+				history = append(history, latest)
+			}
+			return history, nil
 		}
-	}
-	if err == custom_errors.ErrorNoData {
-		log.Println("No current price data. Returning only history data.")
-		err = nil
+		log.Printf("No CBR history for %s: %v\n", ticker, err)
 	}
 
-	return history, err
+	if latestErr != nil {
+		return HistoryEntries{}, latestErr
+	}
+	return HistoryEntries{latest}, nil
 }
 
-func (api *MoexAPI) getCbrfTicker(ticker string) (HistoryEntries, error) {
+func (api *MoexAPI) getCbrfLatest(ctx context.Context, currency string) (HistoryEntry, error) {
 
-	if ticker != "cbrf_usd" && ticker != "cbrf_eur" {
-		return HistoryEntries{}, custom_errors.ErrorNotFound
+	if currency != "usd" && currency != "eur" {
+		return HistoryEntry{}, custom_errors.ErrorNotFound
 	}
 
 	url := fmt.Sprintf("%s/iss/statistics/engines/currency/markets/selt/rates.json?iss.meta=off&"+
 		"cbrf.columns=CBRF_USD_LAST,CBRF_USD_TRADEDATE,CBRF_EUR_LAST,CBRF_EUR_TRADEDATE",
 		api.BaseURL)
 
-	log.Printf("Fetching price data from url %s for %s\n", url, ticker)
-	data, err := utils.HttpGet(url)
+	log.Printf("Fetching price data from url %s for %s\n", url, currency)
+	data, err := api.Fetcher.Get(ctx, url)
 	if err != nil {
-		return HistoryEntries{}, err
+		return HistoryEntry{}, err
 	}
 
 	var moexCbrfJSON MoexCbrfPriceJSON
 	err = json.Unmarshal(data, &moexCbrfJSON)
 	if err != nil {
-		return HistoryEntries{}, err
+		return HistoryEntry{}, err
 	}
 
-	var moexHistoryEntry HistoryEntry
-	if ticker == "cbrf_usd" {
-		moexHistoryEntry.Close = utils.GetFloat64(moexCbrfJSON.Cbrf.Data[0][0])
-		time, err := time.Parse("2006-01-02", moexCbrfJSON.Cbrf.Data[0][1].(string))
-		if err != nil {
-			return HistoryEntries{}, err
+	if len(moexCbrfJSON.Cbrf.Data) == 0 {
+		return HistoryEntry{}, custom_errors.ErrorNoData
+	}
+
+	columns := moexCbrfJSON.Cbrf.Columns
+	entry := moexCbrfJSON.Cbrf.Data[0]
+	prefix := "CBRF_" + strings.ToUpper(currency)
+
+	last := columnValue(columns, entry, prefix+"_LAST")
+	if last == nil {
+		return HistoryEntry{}, custom_errors.ErrorNoData
+	}
+
+	tradeDate, _ := columnValue(columns, entry, prefix+"_TRADEDATE").(string)
+	date, err := time.Parse("2006-01-02", tradeDate)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+
+	return HistoryEntry{
+		Date:      date,
+		Close:     utils.GetFloat64(last),
+		Facevalue: 1,
+	}, nil
+}
+
+func (api *MoexAPI) GetTicker(ctx context.Context, ticker string, options Options) (HistoryEntries, error) {
+	if strings.HasPrefix(ticker, "cbrf_") {
+		return api.getCbrfTicker(ctx, ticker)
+	}
+	return api.getRegularTicker(ctx, ticker, options)
+}
+
+func (api *MoexAPI) GetMetadata(ctx context.Context, ticker string, options Options) (Metadata, error) {
+	metadata := Metadata{
+		Currency:  CURRENCY_RUB,
+		Delayed:   MOEX_DELAYED,
+		Source:    MOEX_SOURCE,
+		SourceURL: api.BaseURL,
+	}
+	if api.Redis.Client != nil {
+		metadata.CacheTTL = int(intervalCacheTTL(options.Interval).Seconds())
+	}
+
+	if strings.HasPrefix(ticker, "cbrf_") {
+		// history comes from CBR, ISS only adds the latest rate
+		metadata.Name = strings.ToUpper(ticker)
+		metadata.Delayed = false
+		if api.Cbr != nil {
+			metadata.Source = CBR_SOURCE
+			metadata.SourceURL = api.Cbr.BaseURL
+		}
+		return metadata, nil
+	}
+
+	security, err := api.getSecurityParameters(ctx, ticker, options)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	if security.Currency != "" {
+		metadata.Currency = strings.ToLower(security.Currency)
+	}
+	metadata.Name = security.Name
+	metadata.Board = security.Board
+	metadata.Market = security.Market
+	metadata.Engine = security.Engine
+	return metadata, nil
+}
+
+type moexMarketdataColumns struct {
+	Last          string
+	Change        string
+	ChangePercent string
+}
+
+// marketdataColumnsFor returns marketdata column names, indices use
+// their own names for the current value and its change.
+func marketdataColumnsFor(market string) moexMarketdataColumns {
+	if market == MOEX_INDEX_MARKET {
+		return moexMarketdataColumns{
+			Last:          "CURRENTVALUE",
+			Change:        "LASTCHANGE",
+			ChangePercent: "LASTCHANGEPRC",
 		}
-		moexHistoryEntry.Date = time
-	} else if ticker == "cbrf_eur" {
-		moexHistoryEntry.Close = utils.GetFloat64(moexCbrfJSON.Cbrf.Data[0][2])
-		time, err := time.Parse("2006-01-02", moexCbrfJSON.Cbrf.Data[0][3].(string))
+	}
+	return moexMarketdataColumns{
+		Last:          "LAST",
+		Change:        "CHANGE",
+		ChangePercent: "LASTTOPREVPRICE",
+	}
+}
+
+// MOEX_DELAYED reports whether ISS market data is delayed.
+// Anonymous ISS clients receive quotes with a 15 minute delay.
+const MOEX_DELAYED = true
+
+func (api *MoexAPI) GetQuote(ctx context.Context, ticker string, options Options) (Quote, error) {
+	if strings.HasPrefix(ticker, "cbrf_") {
+		history, err := api.getCbrfTicker(ctx, ticker)
 		if err != nil {
-			return HistoryEntries{}, err
+			return Quote{}, err
 		}
-		moexHistoryEntry.Date = time
+		return QuoteFromHistory(history)
 	}
 
-	return HistoryEntries{moexHistoryEntry}, nil
+	// the same board as the history of the request
+	security, err := api.getSecurityParameters(ctx, ticker, options)
+	if err != nil {
+		return Quote{}, err
+	}
 
+	marketdataColumns := marketdataColumnsFor(security.Market)
+	url := fmt.Sprintf(
+		"%s/iss/engines/%s/markets/%s/securities/%s.json?iss.meta=off&iss.only=marketdata&"+
+			"marketdata.columns=BOARDID,%s,BID,OFFER,%s,%s,HIGH,LOW,VOLTODAY,UPDATETIME,SYSTIME",
+		api.BaseURL, security.Engine, security.Market, ticker,
+		marketdataColumns.Last, marketdataColumns.Change, marketdataColumns.ChangePercent,
+	)
+	log.Printf("Fetching quote data from url %s for %s\n", url, ticker)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	var moexPriceJSON MoexPriceJSON
+	err = json.Unmarshal(data, &moexPriceJSON)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	columns := moexPriceJSON.Marketdata.Columns
+	for _, entry := range moexPriceJSON.Marketdata.Data {
+		board, _ := columnValue(columns, entry, "BOARDID").(string)
+		if !strings.EqualFold(board, security.Board) {
+			continue
+		}
+
+		last := columnValue(columns, entry, marketdataColumns.Last)
+		if last == nil {
+			return Quote{}, custom_errors.ErrorNoData
+		}
+
+		quote := Quote{
+			Last:          utils.GetFloat64(last),
+			Bid:           utils.GetFloat64(columnValue(columns, entry, "BID")),
+			Offer:         utils.GetFloat64(columnValue(columns, entry, "OFFER")),
+			Change:        utils.GetFloat64(columnValue(columns, entry, marketdataColumns.Change)),
+			ChangePercent: utils.GetFloat64(columnValue(columns, entry, marketdataColumns.ChangePercent)),
+			High:          utils.GetFloat64(columnValue(columns, entry, "HIGH")),
+			Low:           utils.GetFloat64(columnValue(columns, entry, "LOW")),
+			Volume:        uint64(utils.GetFloat64(columnValue(columns, entry, "VOLTODAY"))),
+			Delayed:       MOEX_DELAYED,
+		}
+		quote.UpdateTime = parseMoexUpdateTime(
+			columnValue(columns, entry, "SYSTIME"),
+			columnValue(columns, entry, "UPDATETIME"),
+		)
+		quote.Date = utils.TradeDate(quote.UpdateTime, utils.MoscowLocation)
+
+		return quote, nil
+	}
+
+	return Quote{}, custom_errors.ErrorNotFound
 }
 
-func (api *MoexAPI) GetTicker(ticker string) (HistoryEntries, error) {
-	if strings.HasPrefix(ticker, "cbrf_") {
-		return api.getCbrfTicker(ticker)
+// parseMoexUpdateTime combines the date of SYSTIME with the UPDATETIME clock,
+// both given in Moscow time.
+func parseMoexUpdateTime(systime any, updatetime any) time.Time {
+	now, ok := systime.(string)
+	if !ok {
+		return time.Now()
+	}
+	system, err := time.ParseInLocation("2006-01-02 15:04:05", now, utils.MoscowLocation)
+	if err != nil {
+		return time.Now()
+	}
+
+	clock, ok := updatetime.(string)
+	if !ok {
+		return system
 	}
-	return api.getRegularTicker(ticker)
+	update, err := time.ParseInLocation("2006-01-02 15:04:05",
+		system.Format("2006-01-02")+" "+clock, utils.MoscowLocation)
+	if err != nil {
+		return system
+	}
+	if update.After(system) {
+		update = update.AddDate(0, 0, -1)
+	}
+	return update
+}
+
+func columnValue(columns []string, entry []any, name string) any {
+	for i, column := range columns {
+		if column == name && i < len(entry) {
+			return entry[i]
+		}
+	}
+	return nil
+}
+
+// MOEX_SECURITY_CACHE_VERSION is bumped whenever MoexSecurityParameters
+// changes, so entries cached without the new fields are not read back.
+// Version 1 was cached under the bare ticker, version 2 had no name.
+const MOEX_SECURITY_CACHE_VERSION = 3
+
+func securityParametersCacheKey(ticker string) string {
+	return fmt.Sprintf("security-v%d-%s", MOEX_SECURITY_CACHE_VERSION, ticker)
 }
 
 func (api *MoexAPI) getSecurityParametersFromCache(ticker string) (MoexSecurityParameters, error) {
 	log.Printf("Getting security parameters data from cache for %s\n", ticker)
-	data, err := api.Redis.Client.Get(api.Redis.Context, ticker).Bytes()
+	data, err := api.Redis.Client.Get(api.Redis.Context, securityParametersCacheKey(ticker)).Bytes()
 	if err != nil {
 		log.Printf("No security parameters data from cache for %s\n", ticker)
 		return MoexSecurityParameters{}, err
@@ -181,51 +429,41 @@ func (api *MoexAPI) getSecurityParametersFromCache(ticker string) (MoexSecurityP
 
 func (api *MoexAPI) setSecurityParametersToCache(ticker string, params MoexSecurityParameters) error {
 	log.Printf("Saving security parameters data to cache for %s\n", ticker)
-	return api.Redis.Client.Set(api.Redis.Context, ticker, params, 0).Err()
+	return api.Redis.Client.Set(api.Redis.Context, securityParametersCacheKey(ticker), params, 0).Err()
 }
 
-func (api *MoexAPI) getSecurityParameters(ticker string) (MoexSecurityParameters, error) {
-	var moexJson MoexSecurityParametersJSON
-
-	url := fmt.Sprintf("%s/iss/securities/%s.json?"+
-		"iss.only=boards&iss.meta=off&"+
-		"boards.columns=boardid,market,engine,is_primary",
-		api.BaseURL, ticker)
+func (api *MoexAPI) getSecurityParameters(ctx context.Context, ticker string,
+	options Options) (MoexSecurityParameters, error) {
 
-	var output MoexSecurityParameters
+	// only auto-detected parameters are cached
+	override := options.Board != "" || options.Market != "" || options.Engine != ""
+	full := options.Board != "" && options.Market != "" && options.Engine != ""
 
-	if api.Redis.Client != nil {
+	if api.Redis.Client != nil && !override {
 		output, err := api.getSecurityParametersFromCache(ticker)
 		if err == nil {
 			return output, nil
 		}
 	}
 
-	log.Printf("Getting security parameters data from url %s for %s\n", url, ticker)
-	data, err := utils.HttpGet(url)
-	if err != nil {
-		return MoexSecurityParameters{}, custom_errors.ErrorCouldNotFetchData
-	}
-
-	err = json.Unmarshal(data, &moexJson)
+	moexJson, err := api.getSecurityJSON(ctx, ticker)
 	if err != nil {
-		return MoexSecurityParameters{}, custom_errors.ErrorCouldNotParseJSON
+		return MoexSecurityParameters{}, err
 	}
-
-	for _, entry := range moexJson.Boards.Data {
-		isPrimary := int(entry[3].(float64))
-		if isPrimary == 1 {
-			output.Board = entry[0].(string)
-			output.Market = entry[1].(string)
-			output.Engine = entry[2].(string)
+	output := securityParameters(moexJson, options)
+	if full {
+		// the board is given, the boards only tell its currency
+		if output.Board == "" {
+			output = securityParameters(moexJson, Options{})
 		}
+		output.Board, output.Market, output.Engine = options.Board, options.Market, options.Engine
 	}
 
 	if output.Board == "" || output.Market == "" || output.Engine == "" {
 		return MoexSecurityParameters{}, custom_errors.ErrorNotFound
 	}
 
-	if api.Redis.Client != nil {
+	if api.Redis.Client != nil && !override {
 		err := api.setSecurityParametersToCache(ticker, output)
 		if err != nil {
 			return MoexSecurityParameters{}, err
@@ -235,6 +473,108 @@ func (api *MoexAPI) getSecurityParameters(ticker string) (MoexSecurityParameters
 	return output, err
 }
 
+// getSecurityJSON fetches the boards and the description of ticker.
+func (api *MoexAPI) getSecurityJSON(ctx context.Context, ticker string) (MoexSecurityParametersJSON, error) {
+	var moexJson MoexSecurityParametersJSON
+
+	url := fmt.Sprintf("%s/iss/securities/%s.json?"+
+		"iss.only=boards,description&iss.meta=off&"+
+		"boards.columns=boardid,market,engine,is_primary,currencyid,history_till&"+
+		"description.columns=name,value",
+		api.BaseURL, ticker)
+
+	log.Printf("Getting security parameters data from url %s for %s\n", url, ticker)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return moexJson, custom_errors.ErrorCouldNotFetchData
+	}
+
+	err = json.Unmarshal(data, &moexJson)
+	if err != nil {
+		return moexJson, custom_errors.ErrorCouldNotParseJSON
+	}
+	return moexJson, nil
+}
+
+// description returns the description block by field name.
+func (moexJson MoexSecurityParametersJSON) description() map[string]any {
+	description := map[string]any{}
+	columns := moexJson.Description.Columns
+	for _, entry := range moexJson.Description.Data {
+		name, _ := columnValue(columns, entry, "name").(string)
+		description[name] = columnValue(columns, entry, "value")
+	}
+	return description
+}
+
+// securityParameters picks the board of moexJson for options and names
+// the security.
+func securityParameters(moexJson MoexSecurityParametersJSON, options Options) MoexSecurityParameters {
+	output := selectBoard(moexJson.Boards.Columns, moexJson.Boards.Data, options)
+	output.Name, _ = moexJson.description()["SHORTNAME"].(string)
+	return output
+}
+
+// selectBoard picks the primary board among the boards matching options.
+// When the primary board has no trades, the board with the most recent
+// trades is used instead.
+func selectBoard(columns []string, boards [][]any, options Options) MoexSecurityParameters {
+	var primary, latest, first []any
+	latestTill := ""
+
+	for _, entry := range boards {
+		board, _ := columnValue(columns, entry, "boardid").(string)
+		market, _ := columnValue(columns, entry, "market").(string)
+		engine, _ := columnValue(columns, entry, "engine").(string)
+
+		if (options.Board != "" && !strings.EqualFold(board, options.Board)) ||
+			(options.Market != "" && !strings.EqualFold(market, options.Market)) ||
+			(options.Engine != "" && !strings.EqualFold(engine, options.Engine)) {
+			continue
+		}
+
+		if first == nil {
+			first = entry
+		}
+
+		historyTill, _ := columnValue(columns, entry, "history_till").(string)
+		isPrimary := int(utils.GetFloat64(columnValue(columns, entry, "is_primary")))
+		if isPrimary == 1 && (primary == nil || historyTill != "") {
+			primary = entry
+		}
+		// dates are formatted as YYYY-MM-DD and compare as strings
+		if historyTill > latestTill {
+			latest = entry
+			latestTill = historyTill
+		}
+	}
+
+	entry := first
+	primaryTill, _ := columnValue(columns, primary, "history_till").(string)
+	switch {
+	case primary != nil && primaryTill != "":
+		entry = primary
+	case latest != nil:
+		if primary != nil {
+			log.Printf("Primary board has no trades, using board with the most recent trades\n")
+		}
+		entry = latest
+	case primary != nil:
+		entry = primary
+	}
+
+	var output MoexSecurityParameters
+	if entry == nil {
+		return output
+	}
+	output.Board, _ = columnValue(columns, entry, "boardid").(string)
+	output.Market, _ = columnValue(columns, entry, "market").(string)
+	output.Engine, _ = columnValue(columns, entry, "engine").(string)
+	currency, _ := columnValue(columns, entry, "currencyid").(string)
+	output.Currency = moexCurrency(currency)
+	return output
+}
+
 func (api *MoexAPI) getSecurityHistoryOffsetFromCache(key string) (HistoryEntries, error) {
 	log.Printf("Getting history data from cache for %s\n", key)
 	data, err := api.Redis.Client.Get(api.Redis.Context, key).Bytes()
@@ -259,16 +599,29 @@ func (api *MoexAPI) setSecurityHistoryOffsetToCache(key string,
 	return api.Redis.Client.Set(api.Redis.Context, key, value, duration).Err()
 }
 
-func (api *MoexAPI) getSecurityHistoryOffset(ticker string,
-	params MoexSecurityParameters,
+// untilTomorrow returns the cache duration until the next Moscow day,
+// when exchanges start a new trading date.
+func untilTomorrow() time.Duration {
+	now := time.Now().In(utils.MoscowLocation)
+	tomorrow := time.Date(
+		now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, utils.MoscowLocation,
+	).AddDate(0, 0, 1)
+	return tomorrow.Sub(now)
+}
+
+func (api *MoexAPI) getSecurityHistoryOffset(ctx context.Context, ticker string,
+	params MoexSecurityParameters, closeColumn string,
 	offset uint) (HistoryEntries, error) {
 	url := fmt.Sprintf("%s/iss/history/engines/%s/markets/%s/boards/%s/"+
 		"securities/%s.json?iss.meta=off&start=%d&history.columns=TRADEDATE"+
-		",CLOSE,HIGH,LOW,VOLUME,FACEVALUE",
-		api.BaseURL, params.Engine, params.Market, params.Board, ticker, offset)
+		",%s,HIGH,LOW,VOLUME,FACEVALUE,OPEN",
+		api.BaseURL, params.Engine, params.Market, params.Board, ticker, offset, closeColumn)
 
 	var moexHistory HistoryEntries
 	cacheKey := fmt.Sprintf("%s-%s-%s-%s-%d", params.Board, params.Market, params.Engine, ticker, offset)
+	if closeColumn != MOEX_CLOSE_COLUMN {
+		cacheKey += "-" + strings.ToLower(closeColumn)
+	}
 
 	if api.Redis.Client != nil {
 		moexHistory, err := api.getSecurityHistoryOffsetFromCache(cacheKey)
@@ -278,7 +631,7 @@ func (api *MoexAPI) getSecurityHistoryOffset(ticker string,
 	}
 
 	log.Printf("Fetching history data from url %s for %s\n", url, ticker)
-	data, err := utils.HttpGet(url)
+	data, err := api.Fetcher.Get(ctx, url)
 	if err != nil {
 		return HistoryEntries{}, err
 	}
@@ -296,29 +649,31 @@ func (api *MoexAPI) getSecurityHistoryOffset(ticker string,
 
 	moexHistory = make(HistoryEntries, len(moexHistoryJSON.History.Data))
 
+	// not every market has every column, so columns are looked up by name
+	columns := moexHistoryJSON.History.Columns
 	for i, entry := range moexHistoryJSON.History.Data {
-		time, err := time.Parse("2006-01-02", entry[0].(string))
+		tradeDate, _ := columnValue(columns, entry, "TRADEDATE").(string)
+		time, err := time.Parse("2006-01-02", tradeDate)
 		if err != nil {
 			return HistoryEntries{}, err
 		}
 		moexHistory[i].Date = time
 
-		if entry[1] == nil || entry[2] == nil || entry[3] == nil {
+		closePrice := columnValue(columns, entry, closeColumn)
+		high := columnValue(columns, entry, "HIGH")
+		low := columnValue(columns, entry, "LOW")
+		if closePrice == nil || high == nil || low == nil {
 			continue
 		}
 
-		moexHistory[i].Close = utils.GetFloat64(entry[1])
-		moexHistory[i].High = utils.GetFloat64(entry[2])
-		moexHistory[i].Low = utils.GetFloat64(entry[3].(float64))
+		moexHistory[i].Open = utils.GetFloat64(columnValue(columns, entry, "OPEN"))
+		moexHistory[i].Close = utils.GetFloat64(closePrice)
+		moexHistory[i].High = utils.GetFloat64(high)
+		moexHistory[i].Low = utils.GetFloat64(low)
+		moexHistory[i].Volume = uint64(utils.GetFloat64(columnValue(columns, entry, "VOLUME")))
 
-		if len(entry) > 4 {
-			moexHistory[i].Volume = uint64(utils.GetFloat64(entry[4]))
-		} else {
-			moexHistory[i].Volume = 0
-		}
-
-		if len(entry) > 5 {
-			moexHistory[i].Facevalue = entry[5].(float64)
+		if facevalue := columnValue(columns, entry, "FACEVALUE"); facevalue != nil {
+			moexHistory[i].Facevalue = utils.GetFloat64(facevalue)
 		} else {
 			moexHistory[i].Facevalue = 1.0
 		}
@@ -332,11 +687,7 @@ func (api *MoexAPI) getSecurityHistoryOffset(ticker string,
 
 		} else {
 			// cache until tomorrow
-			now := time.Now().UTC()
-			tomorrow := time.Date(
-				now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC,
-			).AddDate(0, 0, 1)
-			duration = tomorrow.Sub(now)
+			duration = untilTomorrow()
 		}
 		err = api.setSecurityHistoryOffsetToCache(cacheKey, moexHistory, duration)
 		if err != nil {
@@ -347,13 +698,14 @@ func (api *MoexAPI) getSecurityHistoryOffset(ticker string,
 	return moexHistory, nil
 }
 
-func (api *MoexAPI) getSecurityCurrentPrice(ticker string, params MoexSecurityParameters) (HistoryEntry, error) {
+func (api *MoexAPI) getSecurityCurrentPrice(ctx context.Context, ticker string, params MoexSecurityParameters) (HistoryEntry, error) {
+	columns := marketdataColumnsFor(params.Market)
 	url := fmt.Sprintf(
-		"%s/iss/engines/%s/markets/%s/securities/%s.json?iss.meta=off&iss.only=marketdata&marketdata.columns=BOARDID,LAST,HIGH,LOW,VOLTODAY",
-		api.BaseURL, params.Engine, params.Market, ticker,
+		"%s/iss/engines/%s/markets/%s/securities/%s.json?iss.meta=off&iss.only=marketdata&marketdata.columns=BOARDID,%s,HIGH,LOW,VOLTODAY,UPDATETIME,SYSTIME",
+		api.BaseURL, params.Engine, params.Market, ticker, columns.Last,
 	)
 	log.Printf("Fetching price data from url %s for %s\n", url, ticker)
-	data, err := utils.HttpGet(url)
+	data, err := api.Fetcher.Get(ctx, url)
 	if err != nil {
 		return HistoryEntry{}, err
 	}
@@ -364,41 +716,52 @@ func (api *MoexAPI) getSecurityCurrentPrice(ticker string, params MoexSecurityPa
 		return HistoryEntry{}, err
 	}
 
+	marketdataColumns := moexPriceJSON.Marketdata.Columns
 	for _, entry := range moexPriceJSON.Marketdata.Data {
-		if entry[0] != params.Board {
+		board, _ := columnValue(marketdataColumns, entry, "BOARDID").(string)
+		if !strings.EqualFold(board, params.Board) {
 			continue
 		}
 
 		var moexHistory HistoryEntry
 
-		if entry[1] == nil {
+		last := columnValue(marketdataColumns, entry, columns.Last)
+		if last == nil {
 			return HistoryEntry{}, custom_errors.ErrorNoData
-		} else {
-			moexHistory.Close = utils.GetFloat64(entry[1].(float64))
-		}
-
-		if entry[2] != nil {
-			moexHistory.High = utils.GetFloat64(entry[2].(float64))
 		}
+		moexHistory.Close = utils.GetFloat64(last)
+		moexHistory.High = utils.GetFloat64(columnValue(marketdataColumns, entry, "HIGH"))
+		moexHistory.Low = utils.GetFloat64(columnValue(marketdataColumns, entry, "LOW"))
+		moexHistory.Volume = uint64(utils.GetFloat64(columnValue(marketdataColumns, entry, "VOLTODAY")))
+
+		// every session of a trade date, morning to evening, is within one Moscow day
+		updateTime := parseMoexUpdateTime(
+			columnValue(marketdataColumns, entry, "SYSTIME"),
+			columnValue(marketdataColumns, entry, "UPDATETIME"),
+		)
+		moexHistory.Date = utils.TradeDate(updateTime, utils.MoscowLocation)
 
-		if entry[3] != nil {
-			moexHistory.Low = utils.GetFloat64(entry[3])
-		}
-
-		if len(entry) > 4 && entry[4] != nil {
-			moexHistory.Volume = uint64(utils.GetFloat64(entry[4]))
-		} else {
-			moexHistory.Volume = 0
-		}
+		return moexHistory, nil
+	}
 
-		now := time.Now().UTC()
-		year, month, day := now.Date()
+	return HistoryEntry{}, custom_errors.ErrorNotFound
 
-		moexHistory.Date = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
 
-		return moexHistory, nil
+// mergeCurrentPrice reconciles live marketdata with history. Once the trade
+// date of current is in history the history row with the official close is
+// kept, stale marketdata of an earlier date is dropped.
+func mergeCurrentPrice(history HistoryEntries, current HistoryEntry) HistoryEntries {
+	if len(history) == 0 {
+		return append(history, current)
 	}
 
-	return HistoryEntry{}, custom_errors.ErrorNotFound
+	last := history[len(history)-1]
+	if !current.Date.After(last.Date) {
+		log.Printf("Current price of %s is already in history\n", current.Date.Format("2006-01-02"))
+		return history
+	}
 
+	current.Facevalue = last.Facevalue
+	return append(history, current)
 }
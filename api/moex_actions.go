@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+type MoexSplitsJSON struct {
+	Splits struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"splits"`
+}
+
+type MoexDividendsJSON struct {
+	Dividends struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"dividends"`
+}
+
+// GetCorporateActions returns splits and dividends of ticker. They are cached
+// apart from the raw history so adjusted and raw series share history pages.
+func (api *MoexAPI) GetCorporateActions(ctx context.Context, ticker string) (CorporateActions, error) {
+	cacheKey := fmt.Sprintf("actions-%s", ticker)
+
+	if api.Redis.Client != nil {
+		log.Printf("Getting corporate actions from cache for %s\n", cacheKey)
+		data, err := api.Redis.Client.Get(api.Redis.Context, cacheKey).Bytes()
+		if err == nil {
+			var actions CorporateActions
+			err = json.Unmarshal(data, &actions)
+			if err == nil {
+				return actions, nil
+			}
+		}
+		log.Printf("Got no corporate actions from cache for %s\n", cacheKey)
+	}
+
+	splits, err := api.getSplits(ctx, ticker)
+	if err != nil {
+		return CorporateActions{}, err
+	}
+	dividends, err := api.getDividends(ctx, ticker)
+	if err != nil {
+		return CorporateActions{}, err
+	}
+	actions := CorporateActions{
+		Splits:    splits,
+		Dividends: dividends,
+	}
+
+	if api.Redis.Client != nil {
+		duration := untilTomorrow()
+		log.Printf("Saving corporate actions to cache for %s for %d seconds\n", cacheKey, uint64(duration.Seconds()))
+		err = api.Redis.Client.Set(api.Redis.Context, cacheKey, actions, duration).Err()
+		if err != nil {
+			return CorporateActions{}, err
+		}
+	}
+
+	return actions, nil
+}
+
+func (api *MoexAPI) getSplits(ctx context.Context, ticker string) ([]Split, error) {
+	url := fmt.Sprintf("%s/iss/statistics/engines/stock/splits/%s.json?iss.meta=off&"+
+		"splits.columns=tradedate,before,after",
+		api.BaseURL, ticker)
+
+	log.Printf("Fetching splits from url %s for %s\n", url, ticker)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var splitsJSON MoexSplitsJSON
+	err = json.Unmarshal(data, &splitsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := splitsJSON.Splits.Columns
+	var splits []Split
+	for _, entry := range splitsJSON.Splits.Data {
+		tradeDate, _ := columnValue(columns, entry, "tradedate").(string)
+		date, err := time.Parse("2006-01-02", tradeDate)
+		if err != nil {
+			return nil, err
+		}
+		splits = append(splits, Split{
+			Date:   date,
+			Before: utils.GetFloat64(columnValue(columns, entry, "before")),
+			After:  utils.GetFloat64(columnValue(columns, entry, "after")),
+		})
+	}
+
+	return splits, nil
+}
+
+func (api *MoexAPI) getDividends(ctx context.Context, ticker string) ([]Dividend, error) {
+	url := fmt.Sprintf("%s/iss/securities/%s/dividends.json?iss.meta=off&"+
+		"dividends.columns=registryclosedate,value,currencyid",
+		api.BaseURL, ticker)
+
+	log.Printf("Fetching dividends from url %s for %s\n", url, ticker)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var dividendsJSON MoexDividendsJSON
+	err = json.Unmarshal(data, &dividendsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := dividendsJSON.Dividends.Columns
+	var dividends []Dividend
+	for _, entry := range dividendsJSON.Dividends.Data {
+		recordDate, _ := columnValue(columns, entry, "registryclosedate").(string)
+		date, err := time.Parse("2006-01-02", recordDate)
+		if err != nil {
+			return nil, err
+		}
+		currency, _ := columnValue(columns, entry, "currencyid").(string)
+		dividends = append(dividends, Dividend{
+			RecordDate: date,
+			Value:      utils.GetFloat64(columnValue(columns, entry, "value")),
+			Currency:   strings.ToLower(moexCurrency(currency)),
+		})
+	}
+
+	return dividends, nil
+}
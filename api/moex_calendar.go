@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+type MoexCalendarJSON struct {
+	Timetable struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"timetable"`
+	Dailytable struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"dailytable"`
+}
+
+// GetCalendar returns the trading calendar of an ISS engine: the weekly
+// timetable and the dates that differ from it.
+func (api *MoexAPI) GetCalendar(ctx context.Context, engine string) (TradingCalendar, error) {
+	cacheKey := fmt.Sprintf("calendar-%s", engine)
+
+	if api.Redis.Client != nil {
+		log.Printf("Getting calendar from cache for %s\n", cacheKey)
+		data, err := api.Redis.Client.Get(api.Redis.Context, cacheKey).Bytes()
+		if err == nil {
+			var calendar TradingCalendar
+			err = json.Unmarshal(data, &calendar)
+			if err == nil {
+				return calendar, nil
+			}
+		}
+		log.Printf("Got no calendar from cache for %s\n", cacheKey)
+	}
+
+	url := fmt.Sprintf("%s/iss/engines/%s.json?iss.meta=off&iss.only=timetable,dailytable",
+		api.BaseURL, engine)
+
+	log.Printf("Fetching calendar from url %s for %s\n", url, engine)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return TradingCalendar{}, err
+	}
+
+	var calendarJSON MoexCalendarJSON
+	err = json.Unmarshal(data, &calendarJSON)
+	if err != nil {
+		return TradingCalendar{}, err
+	}
+
+	calendar := DefaultCalendar()
+	columns := calendarJSON.Timetable.Columns
+	for _, entry := range calendarJSON.Timetable.Data {
+		// ISS counts week days from 1 for Monday to 7 for Sunday
+		weekDay := int(utils.GetFloat64(columnValue(columns, entry, "week_day")))
+		isWorkDay := utils.GetFloat64(columnValue(columns, entry, "is_work_day")) == 1
+		calendar.WorkDays[time.Weekday(weekDay%7)] = isWorkDay
+	}
+
+	columns = calendarJSON.Dailytable.Columns
+	for _, entry := range calendarJSON.Dailytable.Data {
+		date, _ := columnValue(columns, entry, "date").(string)
+		calendar.Exceptions[date] = utils.GetFloat64(columnValue(columns, entry, "is_work_day")) == 1
+	}
+
+	if api.Redis.Client != nil {
+		duration := untilTomorrow()
+		log.Printf("Saving calendar to cache for %s for %d seconds\n", cacheKey, uint64(duration.Seconds()))
+		err = api.Redis.Client.Set(api.Redis.Context, cacheKey, calendar, duration).Err()
+		if err != nil {
+			return TradingCalendar{}, err
+		}
+	}
+
+	return calendar, nil
+}
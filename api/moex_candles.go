@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+const CANDLES_PAGE_SIZE = 500
+
+type MoexCandlesJSON struct {
+	Candles struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"candles"`
+}
+
+func (api *MoexAPI) getCandles(ctx context.Context, ticker string,
+	security MoexSecurityParameters, interval string) (HistoryEntries, error) {
+
+	from := ""
+	if lookback := INTERVALS[interval].Lookback; lookback != 0 {
+		from = time.Now().In(utils.MoscowLocation).Add(-lookback).Format("2006-01-02")
+	}
+
+	var history HistoryEntries
+	offset := uint(0)
+	for {
+		candles, err := api.getCandlesOffset(ctx, ticker, security, interval, from, offset)
+		if err != nil {
+			log.Println(err)
+			return HistoryEntries{}, err
+		}
+		history = append(history, candles...)
+		if len(candles) != CANDLES_PAGE_SIZE {
+			break
+		}
+		offset += CANDLES_PAGE_SIZE
+	}
+	return history, nil
+}
+
+func (api *MoexAPI) getCandlesOffset(ctx context.Context, ticker string,
+	params MoexSecurityParameters, interval string, from string, offset uint) (HistoryEntries, error) {
+
+	url := fmt.Sprintf("%s/iss/engines/%s/markets/%s/boards/%s/securities/%s/candles.json?"+
+		"iss.meta=off&interval=%d&from=%s&start=%d&candles.columns=begin,open,close,high,low,volume",
+		api.BaseURL, params.Engine, params.Market, params.Board, ticker,
+		INTERVALS[interval].MoexCandles, from, offset)
+
+	cacheKey := fmt.Sprintf("candles-%s-%s-%s-%s-%s-%s-%d",
+		params.Board, params.Market, params.Engine, ticker, interval, from, offset)
+
+	if api.Redis.Client != nil {
+		candles, err := api.getSecurityHistoryOffsetFromCache(cacheKey)
+		if err == nil {
+			return candles, nil
+		}
+	}
+
+	log.Printf("Fetching candles from url %s for %s\n", url, ticker)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return HistoryEntries{}, err
+	}
+
+	var moexCandlesJSON MoexCandlesJSON
+	err = json.Unmarshal(data, &moexCandlesJSON)
+	if err != nil {
+		return HistoryEntries{}, err
+	}
+
+	columns := moexCandlesJSON.Candles.Columns
+	candles := make(HistoryEntries, 0, len(moexCandlesJSON.Candles.Data))
+	for _, entry := range moexCandlesJSON.Candles.Data {
+		begin, _ := columnValue(columns, entry, "begin").(string)
+		date, err := time.ParseInLocation("2006-01-02 15:04:05", begin, utils.MoscowLocation)
+		if err != nil {
+			return HistoryEntries{}, err
+		}
+		if isDated(interval) {
+			date = utils.TradeDate(date, utils.MoscowLocation)
+		}
+		candles = append(candles, HistoryEntry{
+			Date:      date,
+			Open:      utils.GetFloat64(columnValue(columns, entry, "open")),
+			Close:     utils.GetFloat64(columnValue(columns, entry, "close")),
+			High:      utils.GetFloat64(columnValue(columns, entry, "high")),
+			Low:       utils.GetFloat64(columnValue(columns, entry, "low")),
+			Volume:    uint64(utils.GetFloat64(columnValue(columns, entry, "volume"))),
+			Facevalue: 1.0,
+		})
+	}
+
+	if api.Redis.Client != nil {
+		// full pages do not change until the lookback window moves
+		duration := untilTomorrow()
+		if len(candles) != CANDLES_PAGE_SIZE {
+			duration = intervalCacheTTL(interval)
+		}
+		err = api.setSecurityHistoryOffsetToCache(cacheKey, candles, duration)
+		if err != nil {
+			return HistoryEntries{}, err
+		}
+	}
+
+	return candles, nil
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+const MOEX_FUTURES_ENGINE = "futures"
+const MOEX_FUTURES_MARKET = "forts"
+const MOEX_FUTURES_BOARD = "rfud"
+
+const ROLL_EXPIRATION = "expiration"
+const ROLL_VOLUME = "volume"
+
+const BACKADJUST_NONE = "none"
+const BACKADJUST_DIFFERENCE = "difference"
+const BACKADJUST_RATIO = "ratio"
+
+type ContinuousOptions struct {
+	// Roll is ROLL_EXPIRATION or ROLL_VOLUME
+	Roll string
+	// RollDays is how many days before expiration the expiration rule rolls
+	RollDays int
+	// BackAdjust is BACKADJUST_NONE, BACKADJUST_DIFFERENCE or BACKADJUST_RATIO
+	BackAdjust string
+	// From skips contracts expired before this date
+	From time.Time
+}
+
+type futuresContract struct {
+	Ticker     string
+	Expiration time.Time
+}
+
+type MoexFuturesSeriesJSON struct {
+	Series struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"series"`
+}
+
+// GetContinuousFutures stitches front-month contracts of asset into one series.
+func (api *MoexAPI) GetContinuousFutures(ctx context.Context, asset string,
+	options ContinuousOptions) (HistoryEntries, error) {
+
+	contracts, err := api.getFuturesContracts(ctx, asset)
+	if err != nil {
+		return HistoryEntries{}, err
+	}
+
+	var active []futuresContract
+	for _, contract := range contracts {
+		if !contract.Expiration.Before(options.From) {
+			active = append(active, contract)
+		}
+	}
+	if len(active) == 0 {
+		return HistoryEntries{}, custom_errors.ErrorNotFound
+	}
+
+	// contracts are fetched as stitching reaches them, the ones listed after
+	// the next contract are never needed
+	histories := make([]HistoryEntries, len(active))
+	fetched := make([]bool, len(active))
+	history := func(i int) (HistoryEntries, error) {
+		if !fetched[i] {
+			contractHistory, err := api.getContractHistory(ctx, active[i])
+			if err != nil {
+				return HistoryEntries{}, err
+			}
+			histories[i] = contractHistory
+			fetched[i] = true
+		}
+		return histories[i], nil
+	}
+
+	return stitchContracts(active, history, options)
+}
+
+// getContractHistory skips the current price of expired contracts, they have
+// no market data.
+func (api *MoexAPI) getContractHistory(ctx context.Context, contract futuresContract) (HistoryEntries, error) {
+	today := utils.TradeDate(time.Now(), utils.MoscowLocation)
+	if contract.Expiration.Before(today) {
+		security := MoexSecurityParameters{
+			Board:  MOEX_FUTURES_BOARD,
+			Market: MOEX_FUTURES_MARKET,
+			Engine: MOEX_FUTURES_ENGINE,
+		}
+		return api.getSecurityHistory(ctx, contract.Ticker, security, MOEX_CLOSE_COLUMN)
+	}
+
+	return api.getRegularTicker(ctx, contract.Ticker, Options{
+		Board:  MOEX_FUTURES_BOARD,
+		Market: MOEX_FUTURES_MARKET,
+		Engine: MOEX_FUTURES_ENGINE,
+	})
+}
+
+func (api *MoexAPI) getFuturesContracts(ctx context.Context, asset string) ([]futuresContract, error) {
+	url := fmt.Sprintf("%s/iss/statistics/engines/%s/markets/%s/series.json?"+
+		"iss.meta=off&asset_code=%s&show_expired=1&series.columns=secid,last_trade_date",
+		api.BaseURL, MOEX_FUTURES_ENGINE, MOEX_FUTURES_MARKET, asset)
+
+	log.Printf("Fetching futures series from url %s for %s\n", url, asset)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return nil, custom_errors.ErrorCouldNotFetchData
+	}
+
+	var seriesJSON MoexFuturesSeriesJSON
+	err = json.Unmarshal(data, &seriesJSON)
+	if err != nil {
+		return nil, custom_errors.ErrorCouldNotParseJSON
+	}
+
+	columns := seriesJSON.Series.Columns
+	var contracts []futuresContract
+	for _, entry := range seriesJSON.Series.Data {
+		ticker, _ := columnValue(columns, entry, "secid").(string)
+		lastTradeDate, _ := columnValue(columns, entry, "last_trade_date").(string)
+		expiration, err := time.Parse("2006-01-02", lastTradeDate)
+		if ticker == "" || err != nil {
+			continue
+		}
+		contracts = append(contracts, futuresContract{Ticker: ticker, Expiration: expiration})
+	}
+
+	if len(contracts) == 0 {
+		return nil, custom_errors.ErrorNotFound
+	}
+
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].Expiration.Before(contracts[j].Expiration)
+	})
+	return contracts, nil
+}
+
+// stitchContracts takes every contract from the previous roll date until its
+// own roll date. Earlier prices are shifted by the price gap at every roll
+// when back-adjustment is requested. Stitching stops at the first contract
+// whose roll has not happened yet, it is the current front contract.
+func stitchContracts(contracts []futuresContract, history func(int) (HistoryEntries, error),
+	options ContinuousOptions) (HistoryEntries, error) {
+
+	var result HistoryEntries
+	var start time.Time
+
+	for i := range contracts {
+		current, err := history(i)
+		if err != nil {
+			return HistoryEntries{}, err
+		}
+
+		last := i == len(contracts)-1
+		var next HistoryEntries
+		var rollDate time.Time
+		if !last {
+			next, err = history(i + 1)
+			if err != nil {
+				return HistoryEntries{}, err
+			}
+			rollDate = findRollDate(contracts[i], current, next, options)
+			last = !hasPricesSince(next, rollDate)
+		}
+
+		for _, entry := range current {
+			if entry.Close == 0 || entry.Date.Before(start) {
+				continue
+			}
+			if !last && !entry.Date.Before(rollDate) {
+				break
+			}
+			result = append(result, entry)
+		}
+
+		if last {
+			break
+		}
+		if len(result) > 0 {
+			backAdjust(result, next, options.BackAdjust)
+		}
+		start = rollDate
+	}
+
+	return result, nil
+}
+
+func hasPricesSince(history HistoryEntries, date time.Time) bool {
+	for _, entry := range history {
+		if entry.Close != 0 && !entry.Date.Before(date) {
+			return true
+		}
+	}
+	return false
+}
+
+func findRollDate(contract futuresContract, current HistoryEntries, next HistoryEntries,
+	options ContinuousOptions) time.Time {
+
+	rollDate := contract.Expiration.AddDate(0, 0, -options.RollDays)
+	if options.Roll != ROLL_VOLUME {
+		return rollDate
+	}
+
+	volumes := make(map[time.Time]uint64, len(current))
+	for _, entry := range current {
+		volumes[entry.Date] = entry.Volume
+	}
+	for _, entry := range next {
+		if !entry.Date.Before(contract.Expiration) {
+			break
+		}
+		if volume, ok := volumes[entry.Date]; ok && entry.Volume > volume {
+			return entry.Date
+		}
+	}
+	return rollDate
+}
+
+// backAdjust shifts result by the gap between its last close and the close
+// of the next contract on the same date.
+func backAdjust(result HistoryEntries, next HistoryEntries, method string) {
+	if method != BACKADJUST_DIFFERENCE && method != BACKADJUST_RATIO {
+		return
+	}
+
+	last := result[len(result)-1]
+	var nextClose float64
+	for _, entry := range next {
+		if entry.Date.Equal(last.Date) {
+			nextClose = entry.Close
+			break
+		}
+	}
+	if nextClose == 0 || last.Close == 0 {
+		return
+	}
+
+	for i := range result {
+		if method == BACKADJUST_DIFFERENCE {
+			gap := nextClose - last.Close
+			result[i].Open += gap
+			result[i].Close += gap
+			result[i].High += gap
+			result[i].Low += gap
+		} else {
+			scalePrices(&result[i], nextClose/last.Close)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import "testing"
+
+func bars(closes map[string]float64) HistoryEntries {
+	var history HistoryEntries
+	for _, day := range []string{"2024-03-13", "2024-03-14", "2024-03-15", "2024-03-18"} {
+		if close, ok := closes[day]; ok {
+			history = append(history, HistoryEntry{Date: date(day), Open: close - 0.5, Close: close, High: close + 1, Low: close - 1})
+		}
+	}
+	return history
+}
+
+func TestStitchContractsNotRolled(t *testing.T) {
+	contracts := []futuresContract{
+		{Ticker: "SiH4", Expiration: date("2024-03-15")},
+		{Ticker: "SiM4", Expiration: date("2024-06-20")},
+		{Ticker: "SiU4", Expiration: date("2024-09-19")},
+		{Ticker: "SiZ4", Expiration: date("2024-12-19")},
+	}
+	histories := []HistoryEntries{
+		bars(map[string]float64{"2024-03-13": 100, "2024-03-14": 101, "2024-03-15": 102}),
+		bars(map[string]float64{"2024-03-14": 111, "2024-03-15": 112, "2024-03-18": 113}),
+		// listed, but the roll to it is months away
+		bars(map[string]float64{"2024-03-14": 120, "2024-03-15": 121, "2024-03-18": 122}),
+		bars(map[string]float64{"2024-03-18": 130}),
+	}
+	var requested []int
+	history := func(i int) (HistoryEntries, error) {
+		requested = append(requested, i)
+		return histories[i], nil
+	}
+
+	result, err := stitchContracts(contracts, history, ContinuousOptions{
+		Roll:       ROLL_EXPIRATION,
+		BackAdjust: BACKADJUST_DIFFERENCE,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the roll from SiH4 to SiM4 shifts prices
+	want := []float64{110, 111, 112, 113}
+	if len(result) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(result), len(want))
+	}
+	for i, entry := range result {
+		if entry.Close != want[i] || entry.Open != want[i]-0.5 {
+			t.Errorf("entry %d on %s opens at %v and closes at %v, want %v and %v",
+				i, entry.Date.Format("2006-01-02"), entry.Open, entry.Close, want[i]-0.5, want[i])
+		}
+		if entry.Open < entry.Low || entry.Open > entry.High {
+			t.Errorf("entry %d opens at %v outside of [%v, %v]", i, entry.Open, entry.Low, entry.High)
+		}
+	}
+	for _, i := range requested {
+		if i > 2 {
+			t.Errorf("fetched %s which is after the next contract", contracts[i].Ticker)
+		}
+	}
+}
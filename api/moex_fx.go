@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+const MOEX_FX_ENGINE = "currency"
+const MOEX_FX_MARKET = "selt"
+const MOEX_FX_BOARD = "cets"
+const MOEX_WAP_COLUMN = "WAPRICE"
+
+// GetFxTicker returns history of a currency pair such as USD000UTSTOM or CNYRUB_TOM.
+// With wap set the close price is the weighted-average rate and the current
+// rate is taken from the wap_rates block.
+func (api *MoexAPI) GetFxTicker(ctx context.Context, pair string, options Options, wap bool) (HistoryEntries, error) {
+	options.Engine = MOEX_FX_ENGINE
+	options.Market = MOEX_FX_MARKET
+	if options.Board == "" {
+		options.Board = MOEX_FX_BOARD
+	}
+
+	if !wap {
+		return api.getRegularTicker(ctx, pair, options)
+	}
+
+	security := MoexSecurityParameters{
+		Board:  options.Board,
+		Market: options.Market,
+		Engine: options.Engine,
+	}
+	history, err := api.getSecurityHistory(ctx, pair, security, MOEX_WAP_COLUMN)
+	if err != nil {
+		return HistoryEntries{}, err
+	}
+
+	rate, err := api.getWapRate(ctx, pair)
+	if err != nil {
+		log.Printf("No weighted-average rate for %s: %v\n", pair, err)
+		if len(history) == 0 {
+			return HistoryEntries{}, custom_errors.ErrorNotFound
+		}
+		return history, nil
+	}
+	if len(history) == 0 || rate.Date.After(history[len(history)-1].Date) {
+		history = append(history, rate)
+	}
+
+	return history, nil
+}
+
+func (api *MoexAPI) getWapRate(ctx context.Context, pair string) (HistoryEntry, error) {
+	url := fmt.Sprintf("%s/iss/statistics/engines/%s/markets/%s/rates.json?iss.meta=off&"+
+		"iss.only=wap_rates&wap_rates.columns=tradedate,secid,price",
+		api.BaseURL, MOEX_FX_ENGINE, MOEX_FX_MARKET)
+
+	log.Printf("Fetching weighted-average rates from url %s for %s\n", url, pair)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+
+	var moexCbrfJSON MoexCbrfPriceJSON
+	err = json.Unmarshal(data, &moexCbrfJSON)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+
+	columns := moexCbrfJSON.WapRates.Columns
+	for _, entry := range moexCbrfJSON.WapRates.Data {
+		secid, _ := columnValue(columns, entry, "secid").(string)
+		if !strings.EqualFold(secid, pair) {
+			continue
+		}
+
+		price := columnValue(columns, entry, "price")
+		if price == nil {
+			return HistoryEntry{}, custom_errors.ErrorNoData
+		}
+
+		tradeDate, _ := columnValue(columns, entry, "tradedate").(string)
+		date, err := time.Parse("2006-01-02", tradeDate)
+		if err != nil {
+			return HistoryEntry{}, err
+		}
+
+		return HistoryEntry{
+			Date:      date,
+			Close:     utils.GetFloat64(price),
+			Facevalue: 1.0,
+		}, nil
+	}
+
+	return HistoryEntry{}, custom_errors.ErrorNotFound
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+const MOEX_INDEX_ENGINE = "stock"
+const MOEX_INDEX_MARKET = "index"
+
+type MoexIndexConstituent struct {
+	Ticker    string    `json:"ticker"`
+	ShortName string    `json:"shortname"`
+	Weight    float64   `json:"weight"`
+	Date      time.Time `json:"date"`
+}
+
+type MoexIndexConstituents []MoexIndexConstituent
+
+func (constituents MoexIndexConstituents) MarshalBinary() ([]byte, error) {
+	return json.Marshal(constituents)
+}
+
+type MoexIndexAnalyticsJSON struct {
+	Analytics struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"analytics"`
+	Cursor struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"analytics.cursor"`
+}
+
+// GetIndexTicker returns index history, resolving the board among
+// index boards only.
+func (api *MoexAPI) GetIndexTicker(ctx context.Context, index string, options Options) (HistoryEntries, error) {
+	options.Engine = MOEX_INDEX_ENGINE
+	options.Market = MOEX_INDEX_MARKET
+	return api.getRegularTicker(ctx, index, options)
+}
+
+func (api *MoexAPI) GetIndexConstituents(ctx context.Context, index string) (MoexIndexConstituents, error) {
+	cacheKey := fmt.Sprintf("constituents-%s", index)
+
+	if api.Redis.Client != nil {
+		log.Printf("Getting index constituents from cache for %s\n", cacheKey)
+		data, err := api.Redis.Client.Get(api.Redis.Context, cacheKey).Bytes()
+		if err == nil {
+			var constituents MoexIndexConstituents
+			err = json.Unmarshal(data, &constituents)
+			if err == nil {
+				return constituents, nil
+			}
+		}
+		log.Printf("Got no index constituents from cache for %s\n", cacheKey)
+	}
+
+	var constituents MoexIndexConstituents
+	offset := uint(0)
+	for {
+		url := fmt.Sprintf("%s/iss/statistics/engines/%s/markets/%s/analytics/%s.json?"+
+			"iss.meta=off&start=%d&limit=%d&analytics.columns=tradedate,ticker,shortnames,weight",
+			api.BaseURL, MOEX_INDEX_ENGINE, MOEX_INDEX_MARKET, index, offset, PAGE_SIZE)
+
+		log.Printf("Fetching index constituents from url %s for %s\n", url, index)
+		data, err := api.Fetcher.Get(ctx, url)
+		if err != nil {
+			return MoexIndexConstituents{}, err
+		}
+
+		var analyticsJSON MoexIndexAnalyticsJSON
+		err = json.Unmarshal(data, &analyticsJSON)
+		if err != nil {
+			return MoexIndexConstituents{}, err
+		}
+
+		columns := analyticsJSON.Analytics.Columns
+		for _, entry := range analyticsJSON.Analytics.Data {
+			var constituent MoexIndexConstituent
+			constituent.Ticker, _ = columnValue(columns, entry, "ticker").(string)
+			constituent.ShortName, _ = columnValue(columns, entry, "shortnames").(string)
+			constituent.Weight = utils.GetFloat64(columnValue(columns, entry, "weight"))
+			tradeDate, _ := columnValue(columns, entry, "tradedate").(string)
+			date, err := time.Parse("2006-01-02", tradeDate)
+			if err != nil {
+				return MoexIndexConstituents{}, err
+			}
+			constituent.Date = date
+			constituents = append(constituents, constituent)
+		}
+
+		offset += uint(len(analyticsJSON.Analytics.Data))
+		if len(analyticsJSON.Analytics.Data) == 0 || len(analyticsJSON.Cursor.Data) == 0 {
+			break
+		}
+		total := uint(utils.GetFloat64(columnValue(
+			analyticsJSON.Cursor.Columns, analyticsJSON.Cursor.Data[0], "TOTAL")))
+		if offset >= total {
+			break
+		}
+	}
+
+	if len(constituents) == 0 {
+		return MoexIndexConstituents{}, custom_errors.ErrorNotFound
+	}
+
+	if api.Redis.Client != nil {
+		duration := untilTomorrow()
+		log.Printf("Saving index constituents to cache for %s for %d seconds\n", cacheKey, uint64(duration.Seconds()))
+		err := api.Redis.Client.Set(api.Redis.Context, cacheKey, constituents, duration).Err()
+		if err != nil {
+			return MoexIndexConstituents{}, err
+		}
+	}
+
+	return constituents, nil
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+const SEARCH_LIMIT = 20
+
+type MoexSearchResult struct {
+	Ticker       string `json:"ticker"`
+	ShortName    string `json:"shortname"`
+	Name         string `json:"name"`
+	ISIN         string `json:"isin"`
+	Type         string `json:"type"`
+	Group        string `json:"group"`
+	PrimaryBoard string `json:"primary_board"`
+	IsTraded     bool   `json:"is_traded"`
+}
+
+type MoexSecurityInfo struct {
+	Ticker       string  `json:"ticker"`
+	Name         string  `json:"name"`
+	ShortName    string  `json:"shortname"`
+	ISIN         string  `json:"isin"`
+	Type         string  `json:"type"`
+	TypeName     string  `json:"type_name"`
+	Currency     string  `json:"currency"`
+	LotSize      uint64  `json:"lot_size"`
+	FaceValue    float64 `json:"facevalue"`
+	FaceUnit     string  `json:"face_unit"`
+	IssueSize    uint64  `json:"issue_size"`
+	PrimaryBoard string  `json:"primary_board"`
+	Market       string  `json:"market"`
+	Engine       string  `json:"engine"`
+}
+
+type MoexSearchJSON struct {
+	Securities struct {
+		Columns []string `json:"columns"`
+		Data    [][]any  `json:"data"`
+	} `json:"securities"`
+}
+
+// moexCurrency maps ISS currency codes to ISO 4217, ISS still uses SUR for roubles.
+func moexCurrency(currency string) string {
+	if currency == "SUR" {
+		return "RUB"
+	}
+	return currency
+}
+
+func (api *MoexAPI) Search(ctx context.Context, query string) ([]MoexSearchResult, error) {
+	url := fmt.Sprintf("%s/iss/securities.json?iss.meta=off&q=%s&limit=%d&"+
+		"securities.columns=secid,shortname,name,isin,type,group,primary_boardid,is_traded",
+		api.BaseURL, url.QueryEscape(query), SEARCH_LIMIT)
+
+	log.Printf("Searching securities from url %s for %s\n", url, query)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return nil, custom_errors.ErrorCouldNotFetchData
+	}
+
+	var moexSearchJSON MoexSearchJSON
+	err = json.Unmarshal(data, &moexSearchJSON)
+	if err != nil {
+		return nil, custom_errors.ErrorCouldNotParseJSON
+	}
+
+	columns := moexSearchJSON.Securities.Columns
+	results := make([]MoexSearchResult, len(moexSearchJSON.Securities.Data))
+	for i, entry := range moexSearchJSON.Securities.Data {
+		results[i].Ticker, _ = columnValue(columns, entry, "secid").(string)
+		results[i].ShortName, _ = columnValue(columns, entry, "shortname").(string)
+		results[i].Name, _ = columnValue(columns, entry, "name").(string)
+		results[i].ISIN, _ = columnValue(columns, entry, "isin").(string)
+		results[i].Type, _ = columnValue(columns, entry, "type").(string)
+		results[i].Group, _ = columnValue(columns, entry, "group").(string)
+		results[i].PrimaryBoard, _ = columnValue(columns, entry, "primary_boardid").(string)
+		results[i].IsTraded = utils.GetFloat64(columnValue(columns, entry, "is_traded")) == 1
+	}
+
+	return results, nil
+}
+
+// GetInfo describes ticker from the same ISS response getSecurityParameters
+// reads, so the description is not requested twice.
+func (api *MoexAPI) GetInfo(ctx context.Context, ticker string) (MoexSecurityInfo, error) {
+	moexJson, err := api.getSecurityJSON(ctx, ticker)
+	if err != nil {
+		return MoexSecurityInfo{}, err
+	}
+
+	security := securityParameters(moexJson, Options{})
+	description := moexJson.description()
+	if security.Board == "" || len(description) == 0 {
+		return MoexSecurityInfo{}, custom_errors.ErrorNotFound
+	}
+
+	info := MoexSecurityInfo{
+		Currency:     security.Currency,
+		FaceValue:    utils.GetFloat64(description["FACEVALUE"]),
+		IssueSize:    uint64(utils.GetFloat64(description["ISSUESIZE"])),
+		PrimaryBoard: security.Board,
+		Market:       security.Market,
+		Engine:       security.Engine,
+	}
+	info.Ticker, _ = description["SECID"].(string)
+	info.Name, _ = description["NAME"].(string)
+	info.ShortName, _ = description["SHORTNAME"].(string)
+	info.ISIN, _ = description["ISIN"].(string)
+	info.Type, _ = description["TYPE"].(string)
+	info.TypeName, _ = description["TYPENAME"].(string)
+	faceUnit, _ := description["FACEUNIT"].(string)
+	info.FaceUnit = moexCurrency(faceUnit)
+
+	lotSize, currency, err := api.getLotSize(ctx, ticker, security)
+	if err != nil {
+		log.Printf("No lot size data for %s: %v\n", ticker, err)
+	}
+	info.LotSize = lotSize
+	if info.Currency == "" {
+		info.Currency = currency
+	}
+
+	return info, nil
+}
+
+func (api *MoexAPI) getLotSize(ctx context.Context, ticker string,
+	params MoexSecurityParameters) (uint64, string, error) {
+
+	url := fmt.Sprintf("%s/iss/engines/%s/markets/%s/boards/%s/securities/%s.json?"+
+		"iss.meta=off&iss.only=securities&securities.columns=LOTSIZE,CURRENCYID",
+		api.BaseURL, params.Engine, params.Market, params.Board, ticker)
+
+	log.Printf("Getting lot size from url %s for %s\n", url, ticker)
+	data, err := api.Fetcher.Get(ctx, url)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var moexSecuritiesJSON MoexSearchJSON
+	err = json.Unmarshal(data, &moexSecuritiesJSON)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if len(moexSecuritiesJSON.Securities.Data) == 0 {
+		return 0, "", custom_errors.ErrorNoData
+	}
+
+	columns := moexSecuritiesJSON.Securities.Columns
+	entry := moexSecuritiesJSON.Securities.Data[0]
+	lotSize := uint64(utils.GetFloat64(columnValue(columns, entry, "LOTSIZE")))
+	currency, _ := columnValue(columns, entry, "CURRENCYID").(string)
+
+	return lotSize, moexCurrency(currency), nil
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+const sberHistoryPath = "/iss/history/engines/stock/markets/shares/boards/TQBR/securities/sber.json"
+
+func date(value string) time.Time {
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func TestMoexGetTickerPagination(t *testing.T) {
+	server := newFakeServer(t)
+	moex := server.moexAPI(utils.RedisClient{})
+
+	entries, err := moex.GetTicker(context.Background(), "sber", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two history pages of 100 and 3 rows and the current price
+	if len(entries) != 104 {
+		t.Fatalf("got %d entries, want 104", len(entries))
+	}
+	if got := server.requestCount(sberHistoryPath); got != 2 {
+		t.Errorf("fetched %d history pages, want 2", got)
+	}
+	if !entries[0].Date.Equal(date("2024-01-03")) {
+		t.Errorf("first date is %s, want 2024-01-03", entries[0].Date)
+	}
+
+	current := entries[len(entries)-1]
+	if !current.Date.Equal(date("2024-05-25")) {
+		t.Errorf("current price date is %s, want 2024-05-25", current.Date)
+	}
+	// the primary board, not the odd lot one listed first
+	if current.Close != 300.02 || current.Volume != 1234567 {
+		t.Errorf("current price is %+v, want TQBR marketdata", current)
+	}
+	if current.Facevalue != 3 {
+		t.Errorf("current price facevalue is %v, want the last history one", current.Facevalue)
+	}
+}
+
+func TestMoexNilColumns(t *testing.T) {
+	server := newFakeServer(t)
+	moex := server.moexAPI(utils.RedisClient{})
+
+	entries, err := moex.GetTicker(context.Background(), "sber", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empty := 0
+	for _, entry := range entries {
+		if entry.Close == 0 {
+			empty++
+		}
+	}
+	if empty != 1 {
+		t.Fatalf("got %d rows without prices, want the one with null columns", empty)
+	}
+
+	pipeline := NewPipeline(moex.Cbr)
+	normalized, report, err := pipeline.Process(context.Background(), moex, "sber", entries, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Dropped != 1 || len(normalized) != len(entries)-1 {
+		t.Errorf("normalization dropped %d of %d rows, want 1", len(entries)-len(normalized), len(entries))
+	}
+	for _, entry := range normalized {
+		if entry.Close == 0 {
+			t.Errorf("normalized history has an empty row on %s", entry.Date)
+		}
+	}
+}
+
+func TestMoexCache(t *testing.T) {
+	server := newFakeServer(t)
+	moex := server.moexAPI(newFakeRedis(t))
+
+	first, err := moex.GetTicker(context.Background(), "sber", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := moex.GetTicker(context.Background(), "sber", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != len(second) {
+		t.Errorf("cached history has %d entries, want %d", len(second), len(first))
+	}
+	cached := map[string]int{
+		"/iss/securities/sber.json": 1,
+		sberHistoryPath:             2,
+		"/iss/engines/stock.json":   1,
+		// the current price is never cached
+		"/iss/engines/stock/markets/shares/securities/sber.json": 2,
+	}
+	for path, want := range cached {
+		if got := server.requestCount(path); got != want {
+			t.Errorf("requested %s %d times, want %d", path, got, want)
+		}
+	}
+}
+
+func TestMoexErrors(t *testing.T) {
+	server := newFakeServer(t)
+	server.fail("/iss/securities/down.json", http.StatusInternalServerError)
+	moex := server.moexAPI(utils.RedisClient{})
+
+	tests := []struct {
+		ticker string
+		err    error
+	}{
+		{"unknown", custom_errors.ErrorNotFound},
+		{"broken", custom_errors.ErrorCouldNotParseJSON},
+		{"down", custom_errors.ErrorCouldNotFetchData},
+	}
+	for _, test := range tests {
+		_, err := moex.GetTicker(context.Background(), test.ticker, Options{})
+		if err != test.err {
+			t.Errorf("GetTicker(%s) returned %v, want %v", test.ticker, err, test.err)
+		}
+	}
+}
+
+func TestMoexMetadata(t *testing.T) {
+	server := newFakeServer(t)
+	moex := server.moexAPI(utils.RedisClient{})
+
+	metadata, err := moex.GetMetadata(context.Background(), "sber", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Currency != CURRENCY_RUB || metadata.Board != "TQBR" || metadata.Name != "Сбербанк" {
+		t.Errorf("got metadata %+v", metadata)
+	}
+	if metadata.SourceURL != server.URL {
+		t.Errorf("source url is %s, want %s", metadata.SourceURL, server.URL)
+	}
+}
+
+func TestMoexInfo(t *testing.T) {
+	server := newFakeServer(t)
+	moex := server.moexAPI(utils.RedisClient{})
+
+	info, err := moex.GetInfo(context.Background(), "sber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "Сбербанк России ПАО ао" || info.FaceValue != 3 || info.PrimaryBoard != "TQBR" {
+		t.Errorf("got info %+v", info)
+	}
+	if count := server.requestCount("/iss/securities/sber.json"); count != 1 {
+		t.Errorf("requested the security %d times, want once", count)
+	}
+}
+
+func TestMoexQuote(t *testing.T) {
+	server := newFakeServer(t)
+	moex := server.moexAPI(utils.RedisClient{})
+
+	quote, err := moex.GetQuote(context.Background(), "sber", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Last != 300.02 || quote.Bid != 300 || quote.Offer != 300.05 {
+		t.Errorf("got quote %+v", quote)
+	}
+	want := time.Date(2024, 5, 25, 10, 29, 58, 0, utils.MoscowLocation)
+	if !quote.UpdateTime.Equal(want) {
+		t.Errorf("update time is %s, want %s", quote.UpdateTime, want)
+	}
+}
+
+func TestMoexStaleSecurityCache(t *testing.T) {
+	server := newFakeServer(t)
+	redis := newFakeRedis(t)
+	moex := server.moexAPI(redis)
+
+	// entries cached by older versions have no currency and name
+	stale := MoexSecurityParameters{Board: "TQBR", Market: "shares", Engine: "stock"}
+	for _, key := range []string{"sber", "security-v2-sber"} {
+		if err := redis.Client.Set(redis.Context, key, stale, 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	metadata, err := moex.GetMetadata(context.Background(), "sber", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Name != "Сбербанк" || metadata.Currency != CURRENCY_RUB {
+		t.Errorf("got metadata %+v from a stale cache entry", metadata)
+	}
+	if got := server.requestCount("/iss/securities/sber.json"); got != 1 {
+		t.Errorf("requested securities %d times, want 1", got)
+	}
+}
+
+func TestMoexQuoteBoardOverride(t *testing.T) {
+	server := newFakeServer(t)
+	moex := server.moexAPI(utils.RedisClient{})
+
+	quote, err := moex.GetQuote(context.Background(), "sber", Options{Board: "smal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Last != 300.1 {
+		t.Errorf("got last %v, want the SMAL board one", quote.Last)
+	}
+}
+
+func TestMoexFullBoardOverride(t *testing.T) {
+	server := newFakeServer(t)
+	moex := server.moexAPI(utils.RedisClient{})
+
+	options := Options{Board: "smal", Market: "shares", Engine: "stock"}
+	security, err := moex.getSecurityParameters(context.Background(), "sber", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MoexSecurityParameters{Board: "smal", Market: "shares", Engine: "stock", Currency: "RUB", Name: "Сбербанк"}
+	if security != want {
+		t.Errorf("got %+v, want %+v", security, want)
+	}
+
+	options.Board = "unlisted"
+	security, err = moex.getSecurityParameters(context.Background(), "sber", options)
+	if err != nil || security.Board != "unlisted" || security.Currency != "RUB" {
+		t.Errorf("got %+v, %v for a board missing from ISS", security, err)
+	}
+}
+
+func TestMoexWeeklyCandlesDated(t *testing.T) {
+	server := newFakeServer(t)
+	moex := server.moexAPI(utils.RedisClient{})
+
+	entries, err := moex.GetTicker(context.Background(), "sber", Options{Interval: INTERVAL_WEEK})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d candles, want 2", len(entries))
+	}
+	// dated like daily history and SPBEX weekly bars
+	if !entries[0].Date.Equal(date("2024-05-13")) || entries[0].Date.Location() != time.UTC {
+		t.Errorf("first candle is dated %s, want 2024-05-13 UTC", entries[0].Date)
+	}
+
+	location, _ := time.LoadLocation("America/New_York")
+	localized := Localize(entries, location, isDated(INTERVAL_WEEK))
+	if localized[0].Date.Day() != 13 {
+		t.Errorf("localized candle is dated %s, want the same trading date", localized[0].Date)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NormalizationReport counts what Normalize changed in provider history.
+type NormalizationReport struct {
+	// Dropped rows had no close price
+	Dropped int `json:"dropped"`
+	// Duplicates are rows replaced by a later row with the same date
+	Duplicates int `json:"duplicates"`
+	// Reordered is set when rows were not sorted by date
+	Reordered bool `json:"reordered"`
+	// Inconsistent rows had high or low not covering open and close
+	Inconsistent int `json:"inconsistent"`
+	// Unconverted rows were dropped by currency conversion as older than
+	// the first CBR rate
+	Unconverted int `json:"unconverted"`
+}
+
+func (report NormalizationReport) Changed() bool {
+	return report.Dropped > 0 || report.Duplicates > 0 || report.Reordered || report.Inconsistent > 0 ||
+		report.Unconverted > 0
+}
+
+func (report NormalizationReport) String() string {
+	return fmt.Sprintf("dropped=%d; duplicates=%d; reordered=%t; inconsistent=%d; unconverted=%d",
+		report.Dropped, report.Duplicates, report.Reordered, report.Inconsistent, report.Unconverted)
+}
+
+// Normalize drops rows without a close price, sorts rows by date keeping the
+// last row of every date and widens high and low to cover open and close.
+// Rows without high and low, such as CBR rates, are kept as they are.
+func Normalize(entries HistoryEntries) (HistoryEntries, NormalizationReport) {
+	var report NormalizationReport
+
+	normalized := make(HistoryEntries, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Close == 0 {
+			report.Dropped++
+			continue
+		}
+		normalized = append(normalized, entry)
+	}
+
+	if !sort.SliceIsSorted(normalized, func(i, j int) bool {
+		return normalized[i].Date.Before(normalized[j].Date)
+	}) {
+		report.Reordered = true
+		sort.SliceStable(normalized, func(i, j int) bool {
+			return normalized[i].Date.Before(normalized[j].Date)
+		})
+	}
+
+	deduplicated := normalized[:0]
+	for _, entry := range normalized {
+		if n := len(deduplicated); n > 0 && deduplicated[n-1].Date.Equal(entry.Date) {
+			deduplicated[n-1] = entry
+			report.Duplicates++
+			continue
+		}
+		deduplicated = append(deduplicated, entry)
+	}
+
+	for i := range deduplicated {
+		entry := &deduplicated[i]
+		if entry.High == 0 && entry.Low == 0 {
+			continue
+		}
+		high := max(entry.High, entry.Close, entry.Open)
+		low := entry.Low
+		if low == 0 {
+			low = entry.Close
+		}
+		low = min(low, entry.Close)
+		if entry.Open != 0 {
+			low = min(low, entry.Open)
+		}
+		if entry.Low == 0 || high != entry.High || low != entry.Low {
+			report.Inconsistent++
+			entry.High = high
+			entry.Low = low
+		}
+	}
+
+	return deduplicated, report
+}
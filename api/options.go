@@ -0,0 +1,26 @@
+package api
+
+// Options are optional request parameters, providers ignore the ones they do not support.
+type Options struct {
+	Board  string
+	Market string
+	Engine string
+	// Interval is one of INTERVALS keys, empty means daily history
+	Interval string
+	// Period is one of PERIOD_* to resample daily history, empty keeps it as is
+	Period string
+	// Currency converts prices to this currency using CBR rates
+	Currency string
+	// Adjust is one of ADJUST_* to back-adjust prices for corporate actions
+	Adjust string
+	// Fill is FILL_FFILL to emit a row for every calendar day of daily history
+	Fill string
+	// Timezone is an IANA name to present dates in, empty keeps the default format
+	Timezone string
+}
+
+func (options Options) Valid() bool {
+	return ValidInterval(options.Interval) && ValidPeriod(options.Period) &&
+		ValidCurrency(options.Currency) && ValidAdjust(options.Adjust) && ValidFill(options.Fill) &&
+		ValidTimezone(options.Timezone)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/base64"
+	"sort"
+	"time"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+// Paginate returns at most limit ascending entries dated after cursor and the
+// cursor of the next page, which is empty on the last page. A limit below one
+// returns every entry after cursor. Cursors are opaque to clients.
+func Paginate(entries HistoryEntries, cursor string, limit int) (HistoryEntries, string, error) {
+	start := 0
+	if cursor != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return HistoryEntries{}, "", custom_errors.ErrorInvalidCursor
+		}
+		after, err := time.Parse(time.RFC3339Nano, string(decoded))
+		if err != nil {
+			return HistoryEntries{}, "", custom_errors.ErrorInvalidCursor
+		}
+		start = sort.Search(len(entries), func(i int) bool {
+			return entries[i].Date.After(after)
+		})
+	}
+
+	page := entries[start:]
+	if limit < 1 || len(page) <= limit {
+		return page, "", nil
+	}
+
+	page = page[:limit]
+	last := page[len(page)-1].Date.Format(time.RFC3339Nano)
+	return page, base64.RawURLEncoding.EncodeToString([]byte(last)), nil
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+// Pipeline applies the provider independent options to history of any provider.
+type Pipeline struct {
+	Cbr *CbrAPI
+}
+
+func NewPipeline(cbr *CbrAPI) Pipeline {
+	return Pipeline{
+		Cbr: cbr,
+	}
+}
+
+// GetHistory fetches ticker from provider and processes it.
+func (pipeline *Pipeline) GetHistory(ctx context.Context, provider Provider, ticker string,
+	options Options) (HistoryEntries, NormalizationReport, error) {
+
+	entries, err := provider.GetTicker(ctx, ticker, options)
+	if err != nil {
+		return HistoryEntries{}, NormalizationReport{}, err
+	}
+	return pipeline.Process(ctx, provider, ticker, entries, options)
+}
+
+// Process normalizes history of provider and applies options to it.
+func (pipeline *Pipeline) Process(ctx context.Context, provider Provider, ticker string,
+	entries HistoryEntries, options Options) (HistoryEntries, NormalizationReport, error) {
+
+	entries, report := Normalize(entries)
+
+	var metadata Metadata
+	if options.Adjust != "" || options.Currency != "" {
+		var err error
+		metadata, err = provider.GetMetadata(ctx, ticker, options)
+		if err != nil {
+			return HistoryEntries{}, NormalizationReport{}, err
+		}
+	}
+
+	if options.Adjust != "" {
+		actionsProvider, ok := provider.(CorporateActionsProvider)
+		if !ok {
+			return HistoryEntries{}, NormalizationReport{}, custom_errors.ErrorNotAllowed
+		}
+		actions, err := actionsProvider.GetCorporateActions(ctx, ticker)
+		if err != nil {
+			return HistoryEntries{}, NormalizationReport{}, err
+		}
+		entries = Adjust(entries, actions, options.Adjust, metadata.Currency)
+	}
+
+	if options.Currency != "" {
+		var err error
+		percentOfFace := metadata.Market == MOEX_BONDS_MARKET
+		entries, report.Unconverted, err = pipeline.Cbr.Convert(ctx, entries,
+			metadata.Currency, options.Currency, percentOfFace)
+		if err != nil {
+			return HistoryEntries{}, NormalizationReport{}, err
+		}
+	}
+
+	if options.Fill == FILL_FFILL && isDaily(options.Interval) {
+		entries = ForwardFill(entries)
+	}
+
+	if options.Period != "" {
+		entries = Resample(entries, options.Period)
+	}
+
+	if options.Timezone != "" {
+		location, err := time.LoadLocation(options.Timezone)
+		if err != nil {
+			return HistoryEntries{}, NormalizationReport{}, err
+		}
+		entries = Localize(entries, location, isDated(options.Interval))
+	}
+
+	return entries, report, nil
+}
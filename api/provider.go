@@ -0,0 +1,24 @@
+package api
+
+import "context"
+
+type Provider interface {
+	GetTicker(ctx context.Context, ticker string, options Options) (HistoryEntries, error)
+	GetMetadata(ctx context.Context, ticker string, options Options) (Metadata, error)
+}
+
+type Metadata struct {
+	// Currency is the lowercase ISO 4217 code prices are quoted in
+	Currency string `json:"currency"`
+	Name     string `json:"name,omitempty"`
+	Board    string `json:"board,omitempty"`
+	Market   string `json:"market,omitempty"`
+	Engine   string `json:"engine,omitempty"`
+	// Delayed is set when the latest prices are delayed by the exchange
+	Delayed bool `json:"delayed"`
+	// Source and SourceURL attribute the data to its publisher
+	Source    string `json:"source"`
+	SourceURL string `json:"source_url"`
+	// CacheTTL is how many seconds the latest history may be served from cache
+	CacheTTL int `json:"cache_ttl"`
+}
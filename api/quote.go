@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+	"github.com/kiberdruzhinnik/go-exchange-api/utils"
+)
+
+// QUOTE_LOOKBACK_DAYS is how far back providers without live market data
+// look for the latest bar.
+const QUOTE_LOOKBACK_DAYS = 14
+
+type Quote struct {
+	Date          time.Time `json:"date"`
+	Last          float64   `json:"last"`
+	Bid           float64   `json:"bid,omitempty"`
+	Offer         float64   `json:"offer,omitempty"`
+	Change        float64   `json:"change"`
+	ChangePercent float64   `json:"change_percent"`
+	High          float64   `json:"high"`
+	Low           float64   `json:"low"`
+	Volume        uint64    `json:"volume"`
+	UpdateTime    time.Time `json:"update_time"`
+	Delayed       bool      `json:"delayed"`
+}
+
+func (quote Quote) MarshalBinary() ([]byte, error) {
+	return json.Marshal(quote)
+}
+
+type QuoteProvider interface {
+	GetQuote(ctx context.Context, ticker string, options Options) (Quote, error)
+}
+
+// ValidQuoteOptions reports whether options only select the board. Quotes
+// are not converted, resampled or adjusted, so other options are rejected
+// instead of being ignored.
+func ValidQuoteOptions(options Options) bool {
+	return options == Options{Board: options.Board, Market: options.Market, Engine: options.Engine}
+}
+
+// QuoteFromHistory builds a quote from the latest bar of entries.
+func QuoteFromHistory(entries HistoryEntries) (Quote, error) {
+	if len(entries) == 0 {
+		return Quote{}, custom_errors.ErrorNotFound
+	}
+
+	last := entries[len(entries)-1]
+	quote := Quote{
+		Date:       last.Date,
+		Last:       last.Close,
+		High:       last.High,
+		Low:        last.Low,
+		Volume:     last.Volume,
+		UpdateTime: last.Date,
+	}
+
+	if len(entries) > 1 {
+		previous := entries[len(entries)-2].Close
+		quote.Change = last.Close - previous
+		if previous != 0 {
+			quote.ChangePercent = quote.Change / previous * 100
+		}
+	}
+
+	return quote, nil
+}
+
+type QuoteCache struct {
+	Redis utils.RedisClient
+	TTL   time.Duration
+}
+
+func NewQuoteCache(redis utils.RedisClient, ttl time.Duration) QuoteCache {
+	return QuoteCache{
+		Redis: redis,
+		TTL:   ttl,
+	}
+}
+
+func (cache *QuoteCache) GetQuote(ctx context.Context, name string,
+	provider QuoteProvider, ticker string, options Options) (Quote, error) {
+
+	key := fmt.Sprintf("quote-%s-%s", name, ticker)
+	if options.Board != "" || options.Market != "" || options.Engine != "" {
+		key += fmt.Sprintf("-%s-%s-%s", options.Board, options.Market, options.Engine)
+	}
+
+	if cache.Redis.Client != nil {
+		log.Printf("Getting quote from cache for %s\n", key)
+		data, err := cache.Redis.Client.Get(cache.Redis.Context, key).Bytes()
+		if err == nil {
+			var quote Quote
+			err = json.Unmarshal(data, &quote)
+			if err == nil {
+				return quote, nil
+			}
+		}
+		log.Printf("Got no quote from cache for %s\n", key)
+	}
+
+	quote, err := provider.GetQuote(ctx, ticker, options)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	if cache.Redis.Client != nil {
+		log.Printf("Saving quote to cache for %s for %d seconds\n", key, uint64(cache.TTL.Seconds()))
+		err = cache.Redis.Client.Set(cache.Redis.Context, key, quote, cache.TTL).Err()
+		if err != nil {
+			return Quote{}, err
+		}
+	}
+
+	return quote, nil
+}
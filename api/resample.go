@@ -0,0 +1,71 @@
+package api
+
+import "time"
+
+const PERIOD_WEEK = "week"
+const PERIOD_MONTH = "month"
+const PERIOD_QUARTER = "quarter"
+const PERIOD_YEAR = "year"
+
+func ValidPeriod(period string) bool {
+	switch period {
+	case "", PERIOD_WEEK, PERIOD_MONTH, PERIOD_QUARTER, PERIOD_YEAR:
+		return true
+	}
+	return false
+}
+
+// periodStart returns the first day of the period containing date.
+func periodStart(date time.Time, period string) time.Time {
+	year, month, day := date.Date()
+	switch period {
+	case PERIOD_WEEK:
+		// weeks start on Monday
+		weekday := (int(date.Weekday()) + 6) % 7
+		return time.Date(year, month, day-weekday, 0, 0, 0, 0, time.UTC)
+	case PERIOD_MONTH:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	case PERIOD_QUARTER:
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case PERIOD_YEAR:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Resample aggregates ascending entries into period bars. A bar is dated by
+// its last entry, so the bar close keeps the date it was traded on.
+func Resample(entries HistoryEntries, period string) HistoryEntries {
+	if period == "" || len(entries) == 0 {
+		return entries
+	}
+
+	var bars HistoryEntries
+	var current time.Time
+	for _, entry := range entries {
+		start := periodStart(entry.Date, period)
+		if len(bars) == 0 || !start.Equal(current) {
+			current = start
+			bar := entry
+			if bar.Open == 0 {
+				bar.Open = entry.Close
+			}
+			bars = append(bars, bar)
+			continue
+		}
+
+		bar := &bars[len(bars)-1]
+		bar.Date = entry.Date
+		bar.Close = entry.Close
+		bar.Facevalue = entry.Facevalue
+		bar.Volume += entry.Volume
+		if entry.High > bar.High {
+			bar.High = entry.High
+		}
+		if entry.Low != 0 && (bar.Low == 0 || entry.Low < bar.Low) {
+			bar.Low = entry.Low
+		}
+	}
+
+	return bars
+}
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/kiberdruzhinnik/go-exchange-api/constants"
@@ -13,6 +15,7 @@ import (
 
 type SpbexAPI struct {
 	BaseURL string
+	Fetcher *utils.Fetcher
 }
 
 type TimeRange struct {
@@ -32,12 +35,50 @@ type SpbexSecurityJSON struct {
 func NewSpbexAPI() SpbexAPI {
 	return SpbexAPI{
 		BaseURL: constants.SpbexBaseApiURL,
+		Fetcher: utils.DefaultFetcher,
 	}
 }
 
-func (api *SpbexAPI) GetTicker(ticker string) (HistoryEntries, error) {
+func (api *SpbexAPI) GetTicker(ctx context.Context, ticker string, options Options) (HistoryEntries, error) {
+	timeRange := api.getTimeRange()
+	resolution := "D"
+	if settings, ok := INTERVALS[options.Interval]; ok {
+		resolution = settings.SpbexResolution
+		if settings.Lookback != 0 {
+			timeRange.Start = uint64(time.Now().Add(-settings.Lookback).Unix())
+		}
+	}
+	return api.getTickerRange(ctx, ticker, resolution, timeRange)
+}
+
+// SPBEX_CURRENCY is the currency investcab quotes foreign stocks in.
+const SPBEX_CURRENCY = "usd"
+const SPBEX_SOURCE = "SPB Exchange via Investcab"
+
+func (api *SpbexAPI) GetMetadata(ctx context.Context, ticker string, options Options) (Metadata, error) {
+	return Metadata{
+		Currency:  SPBEX_CURRENCY,
+		Name:      strings.ToUpper(ticker),
+		Source:    SPBEX_SOURCE,
+		SourceURL: api.BaseURL,
+	}, nil
+}
+
+func (api *SpbexAPI) GetQuote(ctx context.Context, ticker string, options Options) (Quote, error) {
+	timeRange := api.getTimeRange()
+	timeRange.Start = uint64(time.Now().AddDate(0, 0, -QUOTE_LOOKBACK_DAYS).Unix())
 
-	jsonHistory, err := api.getHistory(ticker)
+	history, err := api.getTickerRange(ctx, ticker, "D", timeRange)
+	if err != nil {
+		return Quote{}, err
+	}
+	return QuoteFromHistory(history)
+}
+
+func (api *SpbexAPI) getTickerRange(ctx context.Context, ticker string,
+	resolution string, timeRange TimeRange) (HistoryEntries, error) {
+
+	jsonHistory, err := api.getHistory(ctx, ticker, resolution, timeRange)
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +87,8 @@ func (api *SpbexAPI) GetTicker(ticker string) (HistoryEntries, error) {
 	for i := 0; i < len(jsonHistory.Time); i++ {
 
 		entry := HistoryEntry{
-			Date:      api.parseTime(int64(jsonHistory.Time[i])),
+			Date:      api.parseTime(int64(jsonHistory.Time[i]), resolution),
+			Open:      jsonHistory.Open[i],
 			Close:     jsonHistory.Close[i],
 			High:      jsonHistory.High[i],
 			Low:       jsonHistory.Low[i],
@@ -59,17 +101,21 @@ func (api *SpbexAPI) GetTicker(ticker string) (HistoryEntries, error) {
 	return historyEntries, nil
 }
 
-func (api *SpbexAPI) parseTime(timestamp int64) time.Time {
-	return time.Unix(timestamp, 0)
+func (api *SpbexAPI) parseTime(timestamp int64, resolution string) time.Time {
+	// intraday bars are reported in exchange time, longer bars as trading dates
+	if resolution != "D" && resolution != "W" && resolution != "M" {
+		return time.Unix(timestamp, 0).In(utils.MoscowLocation)
+	}
+	return utils.TradeDate(time.Unix(timestamp, 0), utils.MoscowLocation)
 }
 
-func (api *SpbexAPI) getHistory(ticker string) (SpbexSecurityJSON, error) {
+func (api *SpbexAPI) getHistory(ctx context.Context, ticker string,
+	resolution string, timeRange TimeRange) (SpbexSecurityJSON, error) {
 
-	timeRange := api.getTimeRange()
-	url := api.getUrl(ticker, "D", timeRange)
+	url := api.getUrl(ticker, resolution, timeRange)
 
 	log.Printf("Fetching history data from url %s for %s\n", url, ticker)
-	data, err := utils.HttpGet(url)
+	data, err := api.Fetcher.Get(ctx, url)
 	if err != nil {
 		return SpbexSecurityJSON{}, err
 	}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+func TestSpbexGetTicker(t *testing.T) {
+	server := newFakeServer(t)
+	spbex := server.spbexAPI()
+
+	entries, err := spbex.GetTicker(context.Background(), "aapl", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+
+	first := entries[0]
+	// daily bars are trading dates whatever the server timezone is
+	if !first.Date.Equal(date("2024-01-02")) || first.Date.Location().String() != "UTC" {
+		t.Errorf("first date is %s, want 2024-01-02 UTC", first.Date)
+	}
+	if first.Open != 187.15 || first.Close != 185.64 || first.High != 188.44 || first.Low != 183.89 {
+		t.Errorf("got first bar %+v", first)
+	}
+}
+
+func TestSpbexNoData(t *testing.T) {
+	server := newFakeServer(t)
+	spbex := server.spbexAPI()
+
+	_, err := spbex.GetTicker(context.Background(), "nodata", Options{})
+	if err != custom_errors.ErrorNotFound {
+		t.Errorf("got %v, want %v", err, custom_errors.ErrorNotFound)
+	}
+}
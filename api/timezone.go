@@ -0,0 +1,33 @@
+package api
+
+import "time"
+
+// ValidTimezone accepts an empty timezone or an IANA name such as
+// America/New_York. Local is rejected so output does not depend on the server.
+func ValidTimezone(timezone string) bool {
+	if timezone == "" {
+		return true
+	}
+	if timezone == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(timezone)
+	return err == nil
+}
+
+// Localize converts dates of entries to location. Daily, weekly and monthly
+// entries are trading dates and keep them at midnight of location, intraday
+// bars keep their instant.
+func Localize(entries HistoryEntries, location *time.Location, daily bool) HistoryEntries {
+	localized := make(HistoryEntries, len(entries))
+	for i, entry := range entries {
+		if daily {
+			year, month, day := entry.Date.Date()
+			entry.Date = time.Date(year, month, day, 0, 0, 0, 0, location)
+		} else {
+			entry.Date = entry.Date.In(location)
+		}
+		localized[i] = entry
+	}
+	return localized
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kiberdruzhinnik/go-exchange-api/api"
@@ -13,9 +17,23 @@ import (
 	"github.com/kiberdruzhinnik/go-exchange-api/utils"
 )
 
+// NORMALIZATION_HEADER reports what api.Normalize changed in the returned history
+const NORMALIZATION_HEADER = "X-Normalization"
+
+// SOURCE_HEADER is the provider that answered, SOURCE_URL_HEADER the
+// upstream mirrors it used.
+const SOURCE_HEADER = "X-Source"
+const SOURCE_URL_HEADER = "X-Source-URL"
+
 var MoexAPI api.MoexAPI
 var SpbexAPI api.SpbexAPI
 var CbrAPI api.CbrAPI
+var Providers map[string]api.Provider
+var QuoteCache api.QuoteCache
+var Pipeline api.Pipeline
+
+var BatchConcurrency int
+var BatchMaxItems int
 
 func init() {
 	var redisClient utils.RedisClient
@@ -37,49 +55,491 @@ func init() {
 		log.Println("Verbose logging enabled")
 	}
 
-	MoexAPI = api.NewMoexAPI(redisClient)
+	global := utils.ClientConfigFromEnv("EXCHANGE_API", utils.DefaultClientConfig)
+
+	CbrAPI = api.NewCbrAPI(redisClient)
+	MoexAPI = api.NewMoexAPI(redisClient, &CbrAPI)
 	SpbexAPI = api.NewSpbexAPI()
-	CbrAPI = api.NewCbrAPI()
+	configureProvider("EXCHANGE_API_MOEX", global, &MoexAPI.BaseURL, &MoexAPI.Fetcher)
+	configureProvider("EXCHANGE_API_SPBEX", global, &SpbexAPI.BaseURL, &SpbexAPI.Fetcher)
+	cbr := global
+	if cbr.UserAgent == "" {
+		cbr.UserAgent = api.CBR_USER_AGENT
+	}
+	configureProvider("EXCHANGE_API_CBR", cbr, &CbrAPI.BaseURL, &CbrAPI.Fetcher)
+	Pipeline = api.NewPipeline(&CbrAPI)
+
+	quoteTTL := utils.GetEnvInt("EXCHANGE_API_QUOTE_TTL", 60)
+	QuoteCache = api.NewQuoteCache(redisClient, time.Duration(quoteTTL)*time.Second)
+
+	Providers = map[string]api.Provider{
+		"moex":  &MoexAPI,
+		"spbex": &SpbexAPI,
+		"cbr":   &CbrAPI,
+	}
+
+	BatchConcurrency = utils.GetEnvInt("EXCHANGE_API_BATCH_CONCURRENCY", 8)
+	BatchMaxItems = utils.GetEnvInt("EXCHANGE_API_BATCH_MAX_ITEMS", 100)
 }
 
 func SanitizedParam(c *gin.Context, param string) string {
-	out := c.Param(param)
-	out = strings.ToLower(out)
-	return utils.StringAllowlist(out)
+	return SanitizeTicker(c.Param(param))
+}
+
+func SanitizeTicker(ticker string) string {
+	return utils.StringAllowlist(strings.ToLower(ticker))
 }
 
-func getBaseTicker(c *gin.Context, apiGetTicker func(string) (api.HistoryEntries, error)) {
+func errorStatus(err error) (int, string) {
+	if err == custom_errors.ErrorNotFound {
+		return http.StatusNotFound, "not found"
+	}
+	return http.StatusBadRequest, "bad request"
+}
+
+func tickerOptions(c *gin.Context) api.Options {
+	return api.Options{
+		Board:  SanitizeTicker(c.Query("board")),
+		Market: SanitizeTicker(c.Query("market")),
+		Engine: SanitizeTicker(c.Query("engine")),
+		// intervals are case sensitive, 1m is a minute and 1M is a month
+		Interval: utils.StringAllowlist(c.Query("interval")),
+		Period:   SanitizeTicker(c.Query("period")),
+		Currency: SanitizeTicker(c.Query("currency")),
+		Adjust:   SanitizeTicker(c.Query("adjust")),
+		Fill:     SanitizeTicker(c.Query("fill")),
+		// IANA names are case sensitive and contain slashes, api.ValidTimezone checks them
+		Timezone: c.Query("tz"),
+	}
+}
+
+func getBaseTicker(c *gin.Context, name string,
+	apiGetTicker func(context.Context, string, api.Options) (api.HistoryEntries, error)) {
 	ticker := SanitizedParam(c, "ticker")
 	log.Printf("Got ticker %s\n", ticker)
-	data, err := apiGetTicker(ticker)
+	options := tickerOptions(c)
+	limit, limitValid := parseLimit(c.Query("limit"))
+	if !options.Valid() || !limitValid {
+		respondStatus(c, http.StatusBadRequest, "bad request")
+		return
+	}
+	ctx, sources := utils.WithSourceRecorder(c.Request.Context())
+	requested := api.Source{Provider: name, Ticker: ticker}
+	data, source, err := api.WithAlternatives(ctx, Providers, requested, options,
+		func(provider api.Provider, source api.Source, options api.Options) (api.HistoryEntries, error) {
+			if source == requested {
+				return apiGetTicker(ctx, source.Ticker, options)
+			}
+			return provider.GetTicker(ctx, source.Ticker, options)
+		})
+	provider := Providers[source.Provider]
+	var report api.NormalizationReport
+	if err == nil {
+		data, report, err = Pipeline.Process(ctx, provider, source.Ticker, data, options)
+	}
+	if err != nil {
+		log.Println(err)
+		respondError(c, err)
+		return
+	}
+	if report.Changed() {
+		log.Printf("Normalized %s: %s\n", ticker, report)
+	}
+	c.Header(NORMALIZATION_HEADER, report.String())
+
+	// v1 keeps the bare array for Portfolio Performance, v2 always wraps it
+	if versionOf(c) == API_V1 && !wantsEnvelope(c) {
+		setSourceHeaders(c, sources, source.Provider)
+		c.JSON(http.StatusOK, historyJSON(c, data))
+		return
+	}
+
+	envelope, err := newEnvelope(ctx, c, provider, source.Ticker, options, data, report, limit)
 	if err != nil {
-		if err == custom_errors.ErrorNotFound {
-			log.Println(err)
-			c.JSON(http.StatusNotFound, gin.H{
-				"status": "not found",
-			})
-			return
-		} else {
-			log.Println(err)
-			c.JSON(http.StatusBadRequest, gin.H{
-				"status": "bad request",
-			})
+		log.Println(err)
+		respondError(c, err)
+		return
+	}
+	setSourceHeaders(c, sources, source.Provider)
+	c.JSON(http.StatusOK, envelope)
+}
+
+// setSourceHeaders reports the providers that answered and the upstream
+// origins their data came from. SOURCE_URL_HEADER is omitted when everything
+// was served from cache.
+func setSourceHeaders(c *gin.Context, sources *utils.SourceRecorder, names ...string) {
+	c.Header(SOURCE_HEADER, strings.Join(names, ", "))
+	if urls := sources.Sources(); len(urls) > 0 {
+		c.Header(SOURCE_URL_HEADER, strings.Join(urls, ", "))
+	}
+}
+
+type EnvelopeJSON struct {
+	// Data is api.HistoryEntries in the history schema of the API version
+	Data          any                     `json:"data"`
+	Metadata      api.Metadata            `json:"metadata"`
+	Normalization api.NormalizationReport `json:"normalization"`
+	// NextCursor is passed as cursor to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func wantsEnvelope(c *gin.Context) bool {
+	envelope, err := strconv.ParseBool(c.Query("envelope"))
+	return err == nil && envelope
+}
+
+// parseLimit parses the page size, empty means no pagination.
+func parseLimit(value string) (int, bool) {
+	if value == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return 0, false
+	}
+	return limit, true
+}
+
+func newEnvelope(ctx context.Context, c *gin.Context, provider api.Provider, ticker string, options api.Options,
+	data api.HistoryEntries, report api.NormalizationReport, limit int) (EnvelopeJSON, error) {
+
+	metadata, err := provider.GetMetadata(ctx, ticker, options)
+	if err != nil {
+		return EnvelopeJSON{}, err
+	}
+	if options.Currency != "" {
+		metadata.Currency = options.Currency
+	}
+
+	page, next, err := api.Paginate(data, c.Query("cursor"), limit)
+	if err != nil {
+		return EnvelopeJSON{}, err
+	}
+
+	return EnvelopeJSON{
+		Data:          historyJSON(c, page),
+		Metadata:      metadata,
+		Normalization: report,
+		NextCursor:    next,
+	}, nil
+}
+
+type BatchRequestJSON struct {
+	Items []api.BatchItem `json:"items"`
+}
+
+type BatchEntryJSON struct {
+	Data          any                      `json:"data,omitempty"`
+	Normalization *api.NormalizationReport `json:"normalization,omitempty"`
+	// Status is the v1 error, Error is the v2 one
+	Status string     `json:"status,omitempty"`
+	Error  *ErrorJSON `json:"error,omitempty"`
+}
+
+func getBatch(c *gin.Context, items []api.BatchItem, key func(api.BatchItem) string) {
+	if len(items) == 0 || len(items) > BatchMaxItems {
+		respondStatus(c, http.StatusBadRequest, "bad request")
+		return
+	}
+
+	options := tickerOptions(c)
+	if !options.Valid() {
+		respondStatus(c, http.StatusBadRequest, "bad request")
+		return
+	}
+
+	for i := range items {
+		items[i].Provider = SanitizeTicker(items[i].Provider)
+		items[i].Ticker = SanitizeTicker(items[i].Ticker)
+	}
+	log.Printf("Got batch of %d tickers\n", len(items))
+
+	ctx, sources := utils.WithSourceRecorder(c.Request.Context())
+	results := api.FetchBatch(ctx, Providers, &Pipeline, items, options, BatchConcurrency)
+
+	output := make(map[string]BatchEntryJSON, len(results))
+	var names []string
+	for _, result := range results {
+		if result.Error != nil {
+			log.Println(result.Error)
+			output[key(result.Item)] = batchError(c, result.Error)
+			continue
+		}
+		if !slices.Contains(names, result.Source.Provider) {
+			names = append(names, result.Source.Provider)
+		}
+		entry := BatchEntryJSON{Data: historyJSON(c, result.Data)}
+		if result.Normalization.Changed() {
+			entry.Normalization = &result.Normalization
 		}
+		output[key(result.Item)] = entry
+	}
+	setSourceHeaders(c, sources, names...)
+	c.JSON(http.StatusOK, output)
+}
+
+func batchError(c *gin.Context, err error) BatchEntryJSON {
+	output := errorJSON(err)
+	if versionOf(c) == API_V1 {
+		return BatchEntryJSON{Status: output.Status}
+	}
+	return BatchEntryJSON{Error: &output}
+}
+
+func postBatch(c *gin.Context) {
+	var request BatchRequestJSON
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Println(err)
+		respondStatus(c, http.StatusBadRequest, "bad request")
 		return
 	}
-	c.JSON(http.StatusOK, data)
+	getBatch(c, request.Items, func(item api.BatchItem) string {
+		return item.Provider + ":" + item.Ticker
+	})
+}
+
+func getProviderBatch(provider string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var items []api.BatchItem
+		for _, ticker := range strings.Split(c.Query("tickers"), ",") {
+			if ticker == "" {
+				continue
+			}
+			items = append(items, api.BatchItem{Provider: provider, Ticker: ticker})
+		}
+		getBatch(c, items, func(item api.BatchItem) string {
+			return item.Ticker
+		})
+	}
 }
 
 func moexGetTicker(c *gin.Context) {
-	getBaseTicker(c, MoexAPI.GetTicker)
+	getBaseTicker(c, "moex", MoexAPI.GetTicker)
+}
+
+func moexGetIndexTicker(c *gin.Context) {
+	getBaseTicker(c, "moex", MoexAPI.GetIndexTicker)
+}
+
+func moexGetFxTicker(c *gin.Context) {
+	price := c.DefaultQuery("price", "close")
+	if price != "close" && price != "wap" {
+		respondStatus(c, http.StatusBadRequest, "bad request")
+		return
+	}
+	getBaseTicker(c, "moex", func(ctx context.Context, pair string, options api.Options) (api.HistoryEntries, error) {
+		return MoexAPI.GetFxTicker(ctx, pair, options, price == "wap")
+	})
 }
 
 func spbexGetTicker(c *gin.Context) {
-	getBaseTicker(c, SpbexAPI.GetTicker)
+	getBaseTicker(c, "spbex", SpbexAPI.GetTicker)
 }
 
 func cbrGetTicker(c *gin.Context) {
-	getBaseTicker(c, CbrAPI.GetTicker)
+	getBaseTicker(c, "cbr", CbrAPI.GetTicker)
+}
+
+func getBaseQuote(c *gin.Context, name string) {
+	ticker := SanitizedParam(c, "ticker")
+	log.Printf("Got quote ticker %s\n", ticker)
+	options := tickerOptions(c)
+	if !options.Valid() || !api.ValidQuoteOptions(options) {
+		respondStatus(c, http.StatusBadRequest, "bad request")
+		return
+	}
+	ctx, sources := utils.WithSourceRecorder(c.Request.Context())
+	quote, source, err := api.WithAlternatives(ctx, Providers, api.Source{Provider: name, Ticker: ticker}, options,
+		func(provider api.Provider, source api.Source, options api.Options) (api.Quote, error) {
+			quoteProvider, ok := provider.(api.QuoteProvider)
+			if !ok {
+				return api.Quote{}, custom_errors.ErrorNotFound
+			}
+			return QuoteCache.GetQuote(ctx, source.Provider, quoteProvider, source.Ticker, options)
+		})
+	if err != nil {
+		log.Println(err)
+		respondError(c, err)
+		return
+	}
+	setSourceHeaders(c, sources, source.Provider)
+	c.JSON(http.StatusOK, quote)
+}
+
+func moexGetQuote(c *gin.Context) {
+	getBaseQuote(c, "moex")
+}
+
+func spbexGetQuote(c *gin.Context) {
+	getBaseQuote(c, "spbex")
+}
+
+func cbrGetQuote(c *gin.Context) {
+	getBaseQuote(c, "cbr")
+}
+
+func moexSearch(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" || len(query) > 100 {
+		respondStatus(c, http.StatusBadRequest, "bad request")
+		return
+	}
+	log.Printf("Got search query %s\n", query)
+	results, err := MoexAPI.Search(c.Request.Context(), query)
+	if err != nil {
+		log.Println(err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+func moexGetInfo(c *gin.Context) {
+	ticker := SanitizedParam(c, "ticker")
+	log.Printf("Got info ticker %s\n", ticker)
+	info, err := MoexAPI.GetInfo(c.Request.Context(), ticker)
+	if err != nil {
+		log.Println(err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+func moexGetIndexConstituents(c *gin.Context) {
+	index := SanitizedParam(c, "ticker")
+	log.Printf("Got index %s\n", index)
+	constituents, err := MoexAPI.GetIndexConstituents(c.Request.Context(), index)
+	if err != nil {
+		log.Println(err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, constituents)
+}
+
+func moexGetContinuousFutures(c *gin.Context) {
+	// asset codes such as Si or BR are case sensitive
+	asset := utils.StringAllowlist(c.Param("asset"))
+	log.Printf("Got futures asset %s\n", asset)
+
+	options := api.ContinuousOptions{
+		Roll:       c.DefaultQuery("roll", api.ROLL_EXPIRATION),
+		BackAdjust: c.DefaultQuery("backadjust", api.BACKADJUST_NONE),
+		From:       time.Now().AddDate(-5, 0, 0),
+	}
+
+	rollDays, err := strconv.Atoi(c.DefaultQuery("roll_days", "5"))
+	valid := err == nil && rollDays >= 0 &&
+		(options.Roll == api.ROLL_EXPIRATION || options.Roll == api.ROLL_VOLUME) &&
+		(options.BackAdjust == api.BACKADJUST_NONE ||
+			options.BackAdjust == api.BACKADJUST_DIFFERENCE ||
+			options.BackAdjust == api.BACKADJUST_RATIO)
+	options.RollDays = rollDays
+
+	if from := c.Query("from"); from != "" {
+		options.From, err = time.Parse("2006-01-02", from)
+		valid = valid && err == nil
+	}
+
+	if !valid {
+		respondStatus(c, http.StatusBadRequest, "bad request")
+		return
+	}
+
+	data, err := MoexAPI.GetContinuousFutures(c.Request.Context(), asset, options)
+	if err != nil {
+		log.Println(err)
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, historyJSON(c, data))
+}
+
+// parseWindows parses a comma separated list of moving average windows.
+func parseWindows(value string) ([]int, bool) {
+	if value == "" {
+		return nil, true
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) > 5 {
+		return nil, false
+	}
+	windows := make([]int, len(parts))
+	for i, part := range parts {
+		window, err := strconv.Atoi(part)
+		if err != nil || window < 1 || window > 500 {
+			return nil, false
+		}
+		windows[i] = window
+	}
+	return windows, true
+}
+
+func getBaseAnalytics(c *gin.Context, name string) {
+	ticker := SanitizedParam(c, "ticker")
+	log.Printf("Got analytics ticker %s\n", ticker)
+	options := tickerOptions(c)
+	sma, smaValid := parseWindows(c.Query("sma"))
+	ema, emaValid := parseWindows(c.Query("ema"))
+
+	// benchmark is given as provider:ticker, e.g. moex:imoex
+	var benchmark *api.Source
+	benchmarkValid := true
+	if value := c.Query("benchmark"); value != "" {
+		provider, symbol, found := strings.Cut(value, ":")
+		benchmark = &api.Source{Provider: SanitizeTicker(provider), Ticker: SanitizeTicker(symbol)}
+		_, benchmarkValid = Providers[benchmark.Provider]
+		benchmarkValid = benchmarkValid && found
+	}
+
+	if !options.Valid() || !api.ValidAnalyticsOptions(options) || !smaValid || !emaValid || !benchmarkValid {
+		respondStatus(c, http.StatusBadRequest, "bad request")
+		return
+	}
+
+	ctx, sources := utils.WithSourceRecorder(c.Request.Context())
+	getHistory := func(provider api.Provider, source api.Source, options api.Options) (api.HistoryEntries, error) {
+		data, _, err := Pipeline.GetHistory(ctx, provider, source.Ticker, options)
+		return data, err
+	}
+	data, source, err := api.WithAlternatives(ctx, Providers, api.Source{Provider: name, Ticker: ticker}, options, getHistory)
+	names := []string{source.Provider}
+	var benchmarkData api.HistoryEntries
+	if err == nil && benchmark != nil {
+		// board overrides only apply to the ticker itself
+		benchmarkOptions := options
+		benchmarkOptions.Board, benchmarkOptions.Market, benchmarkOptions.Engine = "", "", ""
+		var benchmarkSource api.Source
+		benchmarkData, benchmarkSource, err = api.WithAlternatives(ctx, Providers, *benchmark, benchmarkOptions, getHistory)
+		if !slices.Contains(names, benchmarkSource.Provider) {
+			names = append(names, benchmarkSource.Provider)
+		}
+	}
+	if err != nil {
+		log.Println(err)
+		respondError(c, err)
+		return
+	}
+
+	analytics := api.ComputeAnalytics(data, sma, ema)
+	if benchmark != nil {
+		analytics.Correlation = api.Correlation(data, benchmarkData)
+	}
+	setSourceHeaders(c, sources, names...)
+	c.JSON(http.StatusOK, analytics)
+}
+
+func moexGetAnalytics(c *gin.Context) {
+	getBaseAnalytics(c, "moex")
+}
+
+func spbexGetAnalytics(c *gin.Context) {
+	getBaseAnalytics(c, "spbex")
+}
+
+func cbrGetAnalytics(c *gin.Context) {
+	getBaseAnalytics(c, "cbr")
 }
 
 func healthCheck(c *gin.Context) {
@@ -88,11 +548,26 @@ func healthCheck(c *gin.Context) {
 	})
 }
 
-func mountRoutes(app *gin.Engine) {
-	app.GET("/moex/:ticker", moexGetTicker)
-	app.GET("/spbex/:ticker", spbexGetTicker)
-	app.GET("/cbr/:ticker", cbrGetTicker)
-	app.GET("/healthcheck", healthCheck)
+// configureProvider gives a provider its own client configured by prefix_*
+// variables on top of the global ones, every provider is built this way.
+// prefix_URL is a comma separated list of mirrors tried in order, they are
+// always allowed.
+func configureProvider(prefix string, global utils.ClientConfig, baseURL *string, fetcher **utils.Fetcher) {
+	config := utils.ClientConfigFromEnv(prefix, global)
+	mirrors := utils.GetEnvList(prefix+"_URL", nil)
+	if len(mirrors) > 0 {
+		*baseURL = mirrors[0]
+		config.AllowList = append(slices.Clone(config.AllowList), mirrors...)
+	}
+
+	providerFetcher, err := utils.NewFetcherFromConfig(config)
+	if err != nil {
+		log.Fatalf("%s: %v\n", prefix, err)
+	}
+	if len(mirrors) > 1 {
+		providerFetcher.Mirrors = mirrors
+	}
+	*fetcher = providerFetcher
 }
 
 func main() {
@@ -0,0 +1,498 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kiberdruzhinnik/go-exchange-api/api"
+)
+
+const OPENAPI_VERSION = "3.0.3"
+
+//go:embed docs.html
+var docsPage []byte
+
+type ParameterDoc struct {
+	Name        string
+	Description string
+	Schema      map[string]any
+}
+
+// Route is a route with its documentation, the router and the specification
+// are both built from VERSION_ROUTES and serviceRoutes. Path parameters are
+// taken from Path, Response builds the 200 response schema of a version.
+type Route struct {
+	Method     string
+	Path       string
+	Handler    gin.HandlerFunc
+	Tag        string
+	Summary    string
+	Parameters []ParameterDoc
+	Body       any
+	Response   func(schemas *SchemaRegistry, version int) map[string]any
+	// ContentType of the 200 response, application/json when empty
+	ContentType string
+	// Headers of the 200 response
+	Headers []HeaderDoc
+}
+
+type HeaderDoc struct {
+	Name        string
+	Description string
+}
+
+// SOURCE_HEADERS are set by setSourceHeaders.
+var SOURCE_HEADERS = []HeaderDoc{
+	{SOURCE_HEADER, "Comma separated providers that answered, another one than requested after a failover"},
+	{SOURCE_URL_HEADER, "Comma separated upstream origins the data came from, omitted when served from cache"},
+}
+
+type OpenAPIJSON struct {
+	OpenAPI    string                               `json:"openapi"`
+	Info       map[string]any                       `json:"info"`
+	Paths      map[string]map[string]map[string]any `json:"paths"`
+	Components map[string]any                       `json:"components"`
+}
+
+// SchemaRegistry turns Go types into OpenAPI schemas, named structs are
+// stored once in components and referenced.
+type SchemaRegistry struct {
+	Schemas map[string]any
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (registry *SchemaRegistry) SchemaOf(value any) map[string]any {
+	return registry.schemaOfType(reflect.TypeOf(value))
+}
+
+func (registry *SchemaRegistry) schemaOfType(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{
+			"allOf":    []any{registry.schemaOfType(t.Elem())},
+			"nullable": true,
+		}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": registry.schemaOfType(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": registry.schemaOfType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return registry.structSchema(t)
+		}
+		if _, ok := registry.Schemas[t.Name()]; !ok {
+			// placeholder for recursive types
+			registry.Schemas[t.Name()] = map[string]any{}
+			registry.Schemas[t.Name()] = registry.structSchema(t)
+		}
+		return schemaRef(t.Name())
+	}
+	// any
+	return map[string]any{}
+}
+
+func (registry *SchemaRegistry) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, flags, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = registry.schemaOfType(field.Type)
+		if !strings.Contains(flags, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func enumSchema(values ...string) map[string]any {
+	return map[string]any{"type": "string", "enum": values}
+}
+
+func stringSchema() map[string]any {
+	return map[string]any{"type": "string"}
+}
+
+func integerSchema() map[string]any {
+	return map[string]any{"type": "integer", "minimum": 1}
+}
+
+func intervalNames() []string {
+	var intervals []string
+	for interval := range api.INTERVALS {
+		intervals = append(intervals, interval)
+	}
+	sort.Strings(intervals)
+	return intervals
+}
+
+func currencyNames() []string {
+	currencies := []string{api.CURRENCY_RUB}
+	for currency := range api.CBR_CURRENCIES {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+var BOARD_PARAMETERS = []ParameterDoc{
+	{"board", "MOEX board override, e.g. tqbr", stringSchema()},
+	{"market", "MOEX market override, e.g. shares", stringSchema()},
+	{"engine", "MOEX engine override, e.g. stock", stringSchema()},
+}
+
+// HISTORY_PARAMETERS are read by tickerOptions.
+var HISTORY_PARAMETERS = parameters(BOARD_PARAMETERS, []ParameterDoc{
+	{"interval", "Candle interval, daily history by default", enumSchema(intervalNames()...)},
+	{"period", "Resample daily history into bars of this period",
+		enumSchema(api.PERIOD_WEEK, api.PERIOD_MONTH, api.PERIOD_QUARTER, api.PERIOD_YEAR)},
+	{"currency", "Convert prices to this currency using CBR rates", enumSchema(currencyNames()...)},
+	{"adjust", "Back-adjust MOEX prices for corporate actions",
+		enumSchema(api.ADJUST_SPLITS, api.ADJUST_DIVIDENDS, api.ADJUST_TOTAL)},
+	{"fill", "Emit a row for every calendar day of daily history", enumSchema(api.FILL_FFILL)},
+	{"tz", "IANA timezone to present dates in, e.g. America/New_York", stringSchema()},
+})
+
+var PAGE_PARAMETERS = []ParameterDoc{
+	{"envelope", "Wrap history with metadata, always on in v2", map[string]any{"type": "boolean"}},
+	{"limit", "Page size of the envelope", integerSchema()},
+	{"cursor", "next_cursor of the previous page", stringSchema()},
+}
+
+var ANALYTICS_PARAMETERS = []ParameterDoc{
+	{"sma", "Comma separated simple moving average windows, e.g. 50,200", stringSchema()},
+	{"ema", "Comma separated exponential moving average windows", stringSchema()},
+	{"benchmark", "Benchmark to correlate with as provider:ticker, e.g. moex:imoex", stringSchema()},
+}
+
+// without returns group without the parameters called names.
+func without(group []ParameterDoc, names ...string) []ParameterDoc {
+	var output []ParameterDoc
+	for _, parameter := range group {
+		if !slices.Contains(names, parameter.Name) {
+			output = append(output, parameter)
+		}
+	}
+	return output
+}
+
+func parameters(groups ...[]ParameterDoc) []ParameterDoc {
+	var output []ParameterDoc
+	for _, group := range groups {
+		output = append(output, group...)
+	}
+	return output
+}
+
+func historyItems(schemas *SchemaRegistry, version int) map[string]any {
+	if version == API_V1 {
+		return schemas.SchemaOf(LegacyHistoryEntryJSON{})
+	}
+	return schemas.SchemaOf(api.HistoryEntry{})
+}
+
+func envelopeSchema(schemas *SchemaRegistry, version int) map[string]any {
+	return map[string]any{
+		"allOf": []any{
+			schemas.SchemaOf(EnvelopeJSON{}),
+			map[string]any{
+				"properties": map[string]any{
+					"data": map[string]any{"type": "array", "items": historyItems(schemas, version)},
+				},
+			},
+		},
+	}
+}
+
+func historyResponse(schemas *SchemaRegistry, version int) map[string]any {
+	if version == API_V1 {
+		return map[string]any{
+			"oneOf": []any{
+				map[string]any{"type": "array", "items": historyItems(schemas, version)},
+				envelopeSchema(schemas, version),
+			},
+		}
+	}
+	return envelopeSchema(schemas, version)
+}
+
+func bareHistoryResponse(schemas *SchemaRegistry, version int) map[string]any {
+	return map[string]any{"type": "array", "items": historyItems(schemas, version)}
+}
+
+func batchResponse(schemas *SchemaRegistry, version int) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"additionalProperties": map[string]any{
+			"allOf": []any{
+				schemas.SchemaOf(BatchEntryJSON{}),
+				map[string]any{
+					"properties": map[string]any{
+						"data": map[string]any{"type": "array", "items": historyItems(schemas, version)},
+					},
+				},
+			},
+		},
+	}
+}
+
+func typeResponse(value any) func(*SchemaRegistry, int) map[string]any {
+	return func(schemas *SchemaRegistry, version int) map[string]any {
+		return schemas.SchemaOf(value)
+	}
+}
+
+func fixedResponse(schema map[string]any) func(*SchemaRegistry, int) map[string]any {
+	return func(schemas *SchemaRegistry, version int) map[string]any {
+		return schema
+	}
+}
+
+func historyRoute(path string, handler gin.HandlerFunc, tag string, summary string, extra ...ParameterDoc) Route {
+	return Route{
+		Method:     http.MethodGet,
+		Path:       path,
+		Handler:    handler,
+		Tag:        tag,
+		Summary:    summary,
+		Parameters: parameters(HISTORY_PARAMETERS, PAGE_PARAMETERS, extra),
+		Response:   historyResponse,
+		Headers: slices.Concat([]HeaderDoc{
+			{NORMALIZATION_HEADER, "What normalization changed in the history"},
+		}, SOURCE_HEADERS),
+	}
+}
+
+func quoteRoute(path string, handler gin.HandlerFunc, tag string) Route {
+	return Route{
+		Method:     http.MethodGet,
+		Path:       path,
+		Handler:    handler,
+		Tag:        tag,
+		Summary:    "Latest quote",
+		Parameters: BOARD_PARAMETERS,
+		Response:   typeResponse(api.Quote{}),
+		Headers:    SOURCE_HEADERS,
+	}
+}
+
+func analyticsRoute(path string, handler gin.HandlerFunc, tag string) Route {
+	return Route{
+		Method:  http.MethodGet,
+		Path:    path,
+		Handler: handler,
+		Tag:     tag,
+		Summary: "Returns, volatility, drawdown and moving averages",
+		// analytics needs one bar per trading day
+		Parameters: parameters(without(HISTORY_PARAMETERS, "interval", "period", "fill"), ANALYTICS_PARAMETERS),
+		Response:   typeResponse(api.Analytics{}),
+		Headers:    SOURCE_HEADERS,
+	}
+}
+
+func providerBatchRoute(path string, handler gin.HandlerFunc, tag string) Route {
+	return Route{
+		Method:  http.MethodGet,
+		Path:    path,
+		Handler: handler,
+		Tag:     tag,
+		Summary: "History of several tickers",
+		Parameters: parameters([]ParameterDoc{
+			{"tickers", "Comma separated tickers", stringSchema()},
+		}, HISTORY_PARAMETERS),
+		Response: batchResponse,
+		Headers:  SOURCE_HEADERS,
+	}
+}
+
+var pathParameter = regexp.MustCompile(`:(\w+)`)
+
+// openAPIPath converts gin path parameters to OpenAPI ones.
+func openAPIPath(path string) string {
+	return pathParameter.ReplaceAllString(path, "{$1}")
+}
+
+func errorResponses(schemas *SchemaRegistry, version int) map[string]any {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"status": stringSchema()},
+		"required":   []string{"status"},
+	}
+	if version != API_V1 {
+		schema = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"error": schemas.SchemaOf(ErrorJSON{})},
+			"required":   []string{"error"},
+		}
+	}
+	content := map[string]any{"application/json": map[string]any{"schema": schema}}
+	return map[string]any{
+		"400": map[string]any{"description": "Invalid parameters", "content": content},
+		"404": map[string]any{"description": "Unknown ticker", "content": content},
+	}
+}
+
+func operation(schemas *SchemaRegistry, route Route, version int, deprecated bool) map[string]any {
+	var params []any
+	for _, match := range pathParameter.FindAllStringSubmatch(route.Path, -1) {
+		params = append(params, map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   stringSchema(),
+		})
+	}
+	for _, param := range route.Parameters {
+		params = append(params, map[string]any{
+			"name":        param.Name,
+			"in":          "query",
+			"description": param.Description,
+			"schema":      param.Schema,
+		})
+	}
+
+	responses := errorResponses(schemas, version)
+	ok := map[string]any{
+		"description": "OK",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": route.Response(schemas, version)},
+		},
+	}
+	if len(route.Headers) > 0 {
+		headers := map[string]any{}
+		for _, header := range route.Headers {
+			headers[header.Name] = map[string]any{
+				"description": header.Description,
+				"schema":      stringSchema(),
+			}
+		}
+		ok["headers"] = headers
+	}
+	responses["200"] = ok
+
+	output := map[string]any{
+		"tags":       []string{route.Tag},
+		"summary":    route.Summary,
+		"parameters": params,
+		"responses":  responses,
+	}
+	if route.Body != nil {
+		output["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemas.SchemaOf(route.Body)},
+			},
+		}
+	}
+	if deprecated {
+		output["deprecated"] = true
+	}
+	return output
+}
+
+func plainOperation(tag string, summary string, contentType string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"tags":    []string{tag},
+		"summary": summary,
+		"responses": map[string]any{
+			"200": map[string]any{
+				"description": "OK",
+				"content":     map[string]any{contentType: map[string]any{"schema": schema}},
+			},
+		},
+	}
+}
+
+// openAPISpec builds the specification of every route of mountRoutes.
+func openAPISpec() OpenAPIJSON {
+	schemas := &SchemaRegistry{Schemas: map[string]any{}}
+	spec := OpenAPIJSON{
+		OpenAPI: OPENAPI_VERSION,
+		Info: map[string]any{
+			"title":       "go-exchange-api",
+			"version":     "2",
+			"description": "MOEX, SPB Exchange and CBR price history for Portfolio Performance",
+		},
+		Paths: map[string]map[string]map[string]any{},
+	}
+
+	add := func(path string, method string, op map[string]any) {
+		path = openAPIPath(path)
+		if spec.Paths[path] == nil {
+			spec.Paths[path] = map[string]map[string]any{}
+		}
+		spec.Paths[path][strings.ToLower(method)] = op
+	}
+
+	versions := []struct {
+		Prefix     string
+		Version    int
+		Deprecated bool
+	}{
+		{"/v1", API_V1, false},
+		{"/v2", API_V2, false},
+		// legacy root aliases of /v1
+		{"", API_V1, true},
+	}
+	for _, version := range versions {
+		for _, route := range VERSION_ROUTES {
+			add(version.Prefix+route.Path, route.Method, operation(schemas, route, version.Version, version.Deprecated))
+		}
+	}
+
+	for _, route := range serviceRoutes() {
+		contentType := route.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		add(route.Path, route.Method, plainOperation(route.Tag, route.Summary, contentType, route.Response(schemas, 0)))
+	}
+
+	spec.Components = map[string]any{"schemas": schemas.Schemas}
+	return spec
+}
+
+func openAPIGet(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPISpec())
+}
+
+func docsGet(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
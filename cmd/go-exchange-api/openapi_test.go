@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	mountRoutes(app)
+
+	routes := map[string]bool{}
+	for _, route := range app.Routes() {
+		routes[route.Method+" "+openAPIPath(route.Path)] = true
+	}
+
+	documented := map[string]bool{}
+	for path, methods := range openAPISpec().Paths {
+		for method := range methods {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("route %s is not in the OpenAPI specification", route)
+		}
+	}
+	for route := range documented {
+		if !routes[route] {
+			t.Errorf("OpenAPI specification has %s which is not a route", route)
+		}
+	}
+}
+
+func TestOpenAPIPathParameters(t *testing.T) {
+	for path, methods := range openAPISpec().Paths {
+		var expected []string
+		for _, match := range pathParameter.FindAllStringSubmatch(strings.NewReplacer("{", ":", "}", "").Replace(path), -1) {
+			expected = append(expected, match[1])
+		}
+		sort.Strings(expected)
+
+		for method, operation := range methods {
+			var actual []string
+			parameters, _ := operation["parameters"].([]any)
+			for _, parameter := range parameters {
+				parameter := parameter.(map[string]any)
+				if parameter["in"] == "path" {
+					actual = append(actual, parameter["name"].(string))
+				}
+			}
+			sort.Strings(actual)
+
+			if strings.Join(actual, ",") != strings.Join(expected, ",") {
+				t.Errorf("%s %s documents path parameters %v, want %v", method, path, actual, expected)
+			}
+		}
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	mountRoutes(app)
+
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json returned %d", recorder.Code)
+	}
+
+	var spec OpenAPIJSON
+	if err := json.Unmarshal(recorder.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.OpenAPI != OPENAPI_VERSION {
+		t.Errorf("openapi is %q, want %q", spec.OpenAPI, OPENAPI_VERSION)
+	}
+	for _, name := range []string{"HistoryEntry", "LegacyHistoryEntryJSON", "ErrorJSON", "Metadata"} {
+		if _, ok := spec.Components["schemas"].(map[string]any)[name]; !ok {
+			t.Errorf("schema %s is missing", name)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "openapi.json") {
+		t.Errorf("GET /docs returned %d", recorder.Code)
+	}
+}
+
+func TestOpenAPISourceHeaders(t *testing.T) {
+	spec := openAPISpec()
+	for _, path := range []string{"/v2/moex/{ticker}", "/v2/moex/{ticker}/quote", "/v2/moex/{ticker}/analytics", "/v2/moex", "/v2/batch"} {
+		for method, operation := range spec.Paths[path] {
+			ok, _ := operation["responses"].(map[string]any)["200"].(map[string]any)
+			headers, _ := ok["headers"].(map[string]any)
+			for _, header := range []string{SOURCE_HEADER, SOURCE_URL_HEADER} {
+				if _, found := headers[header]; !found {
+					t.Errorf("%s %s does not document %s", method, path, header)
+				}
+			}
+		}
+	}
+}
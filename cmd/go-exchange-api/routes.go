@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kiberdruzhinnik/go-exchange-api/api"
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+const API_V1 = 1
+const API_V2 = 2
+
+const API_VERSION_KEY = "api_version"
+
+// apiVersion stores the API version of a route group for the handlers.
+func apiVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(API_VERSION_KEY, version)
+		c.Next()
+	}
+}
+
+func versionOf(c *gin.Context) int {
+	version := c.GetInt(API_VERSION_KEY)
+	if version == 0 {
+		return API_V1
+	}
+	return version
+}
+
+// LEGACY_DEPRECATED is when the root paths were deprecated in favour of /v1.
+var LEGACY_DEPRECATED = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// deprecated marks responses of a route group as deprecated since the given
+// date with the RFC 9745 Deprecation header and links the same path under
+// successor. A zero sunset omits the Sunset header.
+func deprecated(successor string, since time.Time, sunset time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+		c.Header("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successor, c.Request.URL.Path))
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		c.Next()
+	}
+}
+
+type ErrorJSON struct {
+	Code    int    `json:"code"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// PUBLIC_ERRORS are errors whose text is safe to show to clients.
+var PUBLIC_ERRORS = []error{
+	custom_errors.ErrorNotFound,
+	custom_errors.ErrorNoData,
+	custom_errors.ErrorNotAllowed,
+	custom_errors.ErrorInvalidCursor,
+	custom_errors.ErrorCouldNotFetchData,
+	custom_errors.ErrorCouldNotParseJSON,
+}
+
+func errorJSON(err error) ErrorJSON {
+	code, status := errorStatus(err)
+	output := ErrorJSON{
+		Code:   code,
+		Status: status,
+	}
+	for _, public := range PUBLIC_ERRORS {
+		if err == public {
+			output.Message = err.Error()
+		}
+	}
+	return output
+}
+
+// respondError writes err as {"status": ...} in v1 and as {"error": {...}} in v2.
+func respondError(c *gin.Context, err error) {
+	output := errorJSON(err)
+	if versionOf(c) == API_V1 {
+		c.JSON(output.Code, gin.H{
+			"status": output.Status,
+		})
+		return
+	}
+	c.JSON(output.Code, gin.H{
+		"error": output,
+	})
+}
+
+func respondStatus(c *gin.Context, code int, status string) {
+	if versionOf(c) == API_V1 {
+		c.JSON(code, gin.H{
+			"status": status,
+		})
+		return
+	}
+	c.JSON(code, gin.H{
+		"error": ErrorJSON{
+			Code:   code,
+			Status: status,
+		},
+	})
+}
+
+// LegacyHistoryEntryJSON is the frozen v1 history schema.
+type LegacyHistoryEntryJSON struct {
+	Date      time.Time `json:"date"`
+	Close     float64   `json:"close"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Volume    uint64    `json:"volume"`
+	Facevalue float64   `json:"facevalue"`
+}
+
+// historyJSON returns data in the history schema of the request API version.
+func historyJSON(c *gin.Context, data api.HistoryEntries) any {
+	if versionOf(c) != API_V1 {
+		return data
+	}
+	output := make([]LegacyHistoryEntryJSON, len(data))
+	for i, entry := range data {
+		output[i] = LegacyHistoryEntryJSON{
+			Date:      entry.Date,
+			Close:     entry.Close,
+			High:      entry.High,
+			Low:       entry.Low,
+			Volume:    entry.Volume,
+			Facevalue: entry.Facevalue,
+		}
+	}
+	return output
+}
+
+// VERSION_ROUTES are mounted under every API version by mountVersion and
+// documented by openAPISpec.
+var VERSION_ROUTES = []Route{
+	historyRoute("/moex/:ticker", moexGetTicker, "moex", "MOEX history"),
+	historyRoute("/spbex/:ticker", spbexGetTicker, "spbex", "SPB Exchange history"),
+	historyRoute("/cbr/:ticker", cbrGetTicker, "cbr", "CBR exchange rate history"),
+	{
+		Method:  http.MethodGet,
+		Path:    "/moex/search",
+		Handler: moexSearch,
+		Tag:     "moex",
+		Summary: "Search MOEX securities",
+		Parameters: []ParameterDoc{
+			{"q", "Ticker, name or ISIN", stringSchema()},
+		},
+		Response: typeResponse([]api.MoexSearchResult{}),
+	},
+	historyRoute("/moex/index/:ticker", moexGetIndexTicker, "moex", "MOEX index history"),
+	{
+		Method:   http.MethodGet,
+		Path:     "/moex/index/:ticker/constituents",
+		Handler:  moexGetIndexConstituents,
+		Tag:      "moex",
+		Summary:  "MOEX index constituents and weights",
+		Response: typeResponse(api.MoexIndexConstituents{}),
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/moex/futures/:asset/continuous",
+		Handler: moexGetContinuousFutures,
+		Tag:     "moex",
+		Summary: "Continuous futures series",
+		Parameters: []ParameterDoc{
+			{"roll", "Roll rule", enumSchema(api.ROLL_EXPIRATION, api.ROLL_VOLUME)},
+			{"roll_days", "Days before expiration to roll on", map[string]any{"type": "integer", "minimum": 0}},
+			{"backadjust", "Back-adjustment of the roll gap",
+				enumSchema(api.BACKADJUST_NONE, api.BACKADJUST_DIFFERENCE, api.BACKADJUST_RATIO)},
+			{"from", "First date, YYYY-MM-DD", map[string]any{"type": "string", "format": "date"}},
+		},
+		Response: bareHistoryResponse,
+	},
+	historyRoute("/moex/fx/:ticker", moexGetFxTicker, "moex", "MOEX currency pair history",
+		ParameterDoc{"price", "Close price or weighted average rate", enumSchema("close", "wap")}),
+	{
+		Method:   http.MethodGet,
+		Path:     "/moex/:ticker/info",
+		Handler:  moexGetInfo,
+		Tag:      "moex",
+		Summary:  "MOEX security description",
+		Response: typeResponse(api.MoexSecurityInfo{}),
+	},
+	quoteRoute("/moex/:ticker/quote", moexGetQuote, "moex"),
+	quoteRoute("/spbex/:ticker/quote", spbexGetQuote, "spbex"),
+	quoteRoute("/cbr/:ticker/quote", cbrGetQuote, "cbr"),
+	analyticsRoute("/moex/:ticker/analytics", moexGetAnalytics, "moex"),
+	analyticsRoute("/spbex/:ticker/analytics", spbexGetAnalytics, "spbex"),
+	analyticsRoute("/cbr/:ticker/analytics", cbrGetAnalytics, "cbr"),
+	providerBatchRoute("/moex", getProviderBatch("moex"), "moex"),
+	providerBatchRoute("/spbex", getProviderBatch("spbex"), "spbex"),
+	providerBatchRoute("/cbr", getProviderBatch("cbr"), "cbr"),
+	{
+		Method:     http.MethodPost,
+		Path:       "/batch",
+		Handler:    postBatch,
+		Tag:        "batch",
+		Summary:    "History of tickers of several providers",
+		Parameters: HISTORY_PARAMETERS,
+		Body:       BatchRequestJSON{},
+		Response:   batchResponse,
+		Headers:    SOURCE_HEADERS,
+	},
+}
+
+// serviceRoutes are mounted once at the root. It is a function, as the
+// specification handler reads it.
+func serviceRoutes() []Route {
+	status := map[string]any{"type": "object", "properties": map[string]any{"status": stringSchema()}}
+	return []Route{
+		{
+			Method:   http.MethodGet,
+			Path:     "/healthcheck",
+			Handler:  healthCheck,
+			Tag:      "service",
+			Summary:  "Health check",
+			Response: fixedResponse(status),
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/openapi.json",
+			Handler:  openAPIGet,
+			Tag:      "service",
+			Summary:  "This specification",
+			Response: fixedResponse(map[string]any{"type": "object"}),
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/docs",
+			Handler:     docsGet,
+			Tag:         "service",
+			Summary:     "Documentation page",
+			Response:    fixedResponse(stringSchema()),
+			ContentType: "text/html",
+		},
+	}
+}
+
+func mountVersion(group *gin.RouterGroup) {
+	for _, route := range VERSION_ROUTES {
+		group.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func mountRoutes(app *gin.Engine) {
+	mountVersion(app.Group("/v1", apiVersion(API_V1)))
+	mountVersion(app.Group("/v2", apiVersion(API_V2)))
+	// legacy root paths used by Portfolio Performance feeds are aliases of /v1
+	mountVersion(app.Group("/", apiVersion(API_V1), deprecated("/v1", LEGACY_DEPRECATED, time.Time{})))
+	for _, route := range serviceRoutes() {
+		app.Handle(route.Method, route.Path, route.Handler)
+	}
+}
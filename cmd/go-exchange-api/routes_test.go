@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kiberdruzhinnik/go-exchange-api/api"
+	custom_errors "github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   int
+		status string
+	}{
+		{custom_errors.ErrorNotFound, http.StatusNotFound, "not found"},
+		{custom_errors.ErrorCouldNotFetchData, http.StatusBadRequest, "bad request"},
+		{custom_errors.ErrorInvalidCursor, http.StatusBadRequest, "bad request"},
+		{errors.New("dial tcp: connection refused"), http.StatusBadRequest, "bad request"},
+	}
+	for _, test := range tests {
+		code, status := errorStatus(test.err)
+		if code != test.code || status != test.status {
+			t.Errorf("errorStatus(%v) = %d %s, want %d %s", test.err, code, status, test.code, test.status)
+		}
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, version := range []int{API_V1, API_V2} {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Set(API_VERSION_KEY, version)
+		respondError(c, errors.New("dial tcp 10.0.0.1: connection refused"))
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("v%d responded %d, want %d", version, recorder.Code, http.StatusBadRequest)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if version == API_V1 && body["status"] != "bad request" {
+			t.Errorf("v1 error body is %v", body)
+		}
+		if version == API_V2 {
+			output, _ := body["error"].(map[string]any)
+			// internal errors are not shown to clients
+			if output["status"] != "bad request" || output["message"] != nil {
+				t.Errorf("v2 error body is %v", body)
+			}
+		}
+	}
+}
+
+func TestAnalyticsRejectsBarOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	mountRoutes(app)
+
+	for _, query := range []string{"interval=1h", "period=week", "fill=ffill"} {
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v2/moex/sber/analytics?"+query, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("analytics with %s responded %d, want %d", query, recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestQuoteRejectsHistoryOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	mountRoutes(app)
+
+	for _, query := range []string{"currency=usd", "interval=1h", "period=week", "adjust=splits", "fill=ffill", "tz=UTC"} {
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v2/moex/sber/quote?"+query, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("quote with %s responded %d, want %d", query, recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestLegacyDeprecationHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	mountRoutes(app)
+
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/moex/sber/analytics?fill=ffill", nil))
+
+	if got := recorder.Header().Get("Deprecation"); got != "@1792368000" {
+		t.Errorf("Deprecation is %q, want an RFC 9745 date", got)
+	}
+	if got := recorder.Header().Get("Link"); got != `</v1/moex/sber/analytics>; rel="successor-version"` {
+		t.Errorf("Link is %q", got)
+	}
+}
+
+type stubProvider struct {
+	quote api.Quote
+	err   error
+}
+
+func (provider stubProvider) GetTicker(ctx context.Context, ticker string, options api.Options) (api.HistoryEntries, error) {
+	return api.HistoryEntries{}, provider.err
+}
+
+func (provider stubProvider) GetMetadata(ctx context.Context, ticker string, options api.Options) (api.Metadata, error) {
+	return api.Metadata{}, provider.err
+}
+
+func (provider stubProvider) GetQuote(ctx context.Context, ticker string, options api.Options) (api.Quote, error) {
+	return provider.quote, provider.err
+}
+
+func TestQuoteFailover(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	mountRoutes(app)
+
+	providers := Providers
+	t.Cleanup(func() { Providers = providers })
+
+	tests := []struct {
+		moex   error
+		code   int
+		source string
+	}{
+		{custom_errors.ErrorCouldNotFetchData, http.StatusOK, "cbr"},
+		{custom_errors.ErrorNotFound, http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		Providers = map[string]api.Provider{
+			"moex": stubProvider{err: test.moex},
+			"cbr":  stubProvider{quote: api.Quote{Last: 90.37}},
+		}
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v2/moex/cbrf_usd/quote", nil))
+		if recorder.Code != test.code || recorder.Header().Get(SOURCE_HEADER) != test.source {
+			t.Errorf("quote after %v responded %d from %q, want %d from %q",
+				test.moex, recorder.Code, recorder.Header().Get(SOURCE_HEADER), test.code, test.source)
+		}
+	}
+}
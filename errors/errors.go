@@ -11,3 +11,5 @@ var ErrorRedisNotConnected = errors.New("redis is not connected")
 var ErrorRedisNotFound = errors.New("not found in redis")
 
 var ErrorNotAllowed = errors.New("not allowed")
+
+var ErrorInvalidCursor = errors.New("invalid cursor")
@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ClientConfig configures outbound requests to the exchanges.
+type ClientConfig struct {
+	// UserAgent is sent with every request, empty sends the Go default.
+	UserAgent string
+	// Proxy is an http, https or socks5 URL. Empty uses HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY from the environment.
+	Proxy string
+	// Timeout limits a whole request including reading the body.
+	Timeout               time.Duration
+	DialTimeout           time.Duration
+	ResponseHeaderTimeout time.Duration
+	AllowList             []string
+	RateLimit             RateLimitConfig
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and its key.
+	CertFile string
+	KeyFile  string
+	// Resolve dials the address of a host:port key instead of resolving it,
+	// like curl --resolve.
+	Resolve map[string]string
+	HTTP2   bool
+}
+
+var DefaultClientConfig = ClientConfig{
+	Timeout:               60 * time.Second,
+	DialTimeout:           10 * time.Second,
+	ResponseHeaderTimeout: 30 * time.Second,
+	AllowList:             URLS_ALLOW_LIST,
+	RateLimit:             DefaultRateLimitConfig,
+	HTTP2:                 true,
+}
+
+// ClientConfigFromEnv reads prefix_PROXY, prefix_CA_FILE and the other
+// settings, the ones that are not set are taken from fallback.
+func ClientConfigFromEnv(prefix string, fallback ClientConfig) ClientConfig {
+	env := func(name string) string {
+		return prefix + "_" + name
+	}
+	return ClientConfig{
+		UserAgent:             GetEnvString(env("USER_AGENT"), fallback.UserAgent),
+		Proxy:                 GetEnvString(env("PROXY"), fallback.Proxy),
+		Timeout:               GetEnvDuration(env("HTTP_TIMEOUT"), fallback.Timeout),
+		DialTimeout:           GetEnvDuration(env("HTTP_DIAL_TIMEOUT"), fallback.DialTimeout),
+		ResponseHeaderTimeout: GetEnvDuration(env("HTTP_RESPONSE_HEADER_TIMEOUT"), fallback.ResponseHeaderTimeout),
+		AllowList:             GetEnvList(env("ALLOW_LIST"), fallback.AllowList),
+		RateLimit: RateLimitConfig{
+			RPS:         GetEnvFloat64(env("RATE_LIMIT_RPS"), fallback.RateLimit.RPS),
+			Burst:       GetEnvInt(env("RATE_LIMIT_BURST"), fallback.RateLimit.Burst),
+			Concurrency: GetEnvInt(env("RATE_LIMIT_CONCURRENCY"), fallback.RateLimit.Concurrency),
+		},
+		CAFile:   GetEnvString(env("CA_FILE"), fallback.CAFile),
+		CertFile: GetEnvString(env("CLIENT_CERT"), fallback.CertFile),
+		KeyFile:  GetEnvString(env("CLIENT_KEY"), fallback.KeyFile),
+		Resolve:  getEnvResolve(env("RESOLVE"), fallback.Resolve),
+		HTTP2:    GetEnvBool(env("HTTP2"), fallback.HTTP2),
+	}
+}
+
+// getEnvResolve parses host:port=address:port pairs separated by commas.
+func getEnvResolve(name string, fallback map[string]string) map[string]string {
+	list := GetEnvList(name, nil)
+	if list == nil {
+		return fallback
+	}
+	resolve := map[string]string{}
+	for _, item := range list {
+		host, address, ok := strings.Cut(item, "=")
+		if !ok {
+			log.Printf("Invalid value %q for %s, ignoring it\n", item, name)
+			continue
+		}
+		resolve[strings.TrimSpace(host)] = strings.TrimSpace(address)
+	}
+	return resolve
+}
+
+func (config ClientConfig) Transport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.Proxy != "" {
+		proxy, err := url.Parse(config.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %q", config.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	resolve := config.Resolve
+	transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		if override, ok := resolve[address]; ok {
+			address = override
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+	transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if !config.HTTP2 {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		transport.Protocols = protocols
+	}
+
+	return transport, nil
+}
+
+func (config ClientConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// NewClient returns a rate limited client for config.
+func NewClient(config ClientConfig) (*http.Client, error) {
+	transport, err := config.Transport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &RateLimitedTransport{
+			Base:    transport,
+			Limiter: NewRateLimiter(config.RateLimit),
+		},
+		Timeout: config.Timeout,
+	}, nil
+}
+
+// NewFetcherFromConfig returns a Fetcher with its own client for config.
+func NewFetcherFromConfig(config ClientConfig) (*Fetcher, error) {
+	client, err := NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	fetcher := NewFetcher(client, config.AllowList...)
+	fetcher.UserAgent = config.UserAgent
+	return fetcher, nil
+}
+
+// DefaultFetcher uses DefaultClientConfig, providers use it until they are
+// given their own Fetcher. The default config reads no files, so building it
+// does not fail.
+var DefaultFetcher = func() *Fetcher {
+	fetcher, err := NewFetcherFromConfig(DefaultClientConfig)
+	if err != nil {
+		panic(err)
+	}
+	return fetcher
+}()
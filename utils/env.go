@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func GetEnvFloat64(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using %v\n", value, name, fallback)
+		return fallback
+	}
+	return f
+}
+
+func GetEnvInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using %v\n", value, name, fallback)
+		return fallback
+	}
+	return i
+}
+
+// GetEnvDuration parses values like 30s or 1m30s.
+func GetEnvDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using %v\n", value, name, fallback)
+		return fallback
+	}
+	return d
+}
+
+// GetEnvList splits a comma separated value and drops empty items.
+func GetEnvList(name string, fallback []string) []string {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func GetEnvString(name string, fallback string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func GetEnvBool(name string, fallback bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using %v\n", value, name, fallback)
+		return fallback
+	}
+	return b
+}
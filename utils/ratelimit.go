@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+type RateLimitConfig struct {
+	RPS         float64
+	Burst       int
+	Concurrency int
+}
+
+var DefaultRateLimitConfig = RateLimitConfig{
+	RPS:         5,
+	Burst:       10,
+	Concurrency: 4,
+}
+
+type hostLimiter struct {
+	limiter *rate.Limiter
+	slots   chan struct{}
+}
+
+// RateLimiter throttles outbound requests separately for every upstream host.
+type RateLimiter struct {
+	config RateLimitConfig
+	mutex  sync.Mutex
+	hosts  map[string]*hostLimiter
+}
+
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	if config.Burst < 1 {
+		config.Burst = 1
+	}
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	return &RateLimiter{
+		config: config,
+		hosts:  map[string]*hostLimiter{},
+	}
+}
+
+func (l *RateLimiter) getHost(host string) *hostLimiter {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	h, ok := l.hosts[host]
+	if !ok {
+		limit := rate.Limit(l.config.RPS)
+		if l.config.RPS <= 0 {
+			limit = rate.Inf
+		}
+		h = &hostLimiter{
+			limiter: rate.NewLimiter(limit, l.config.Burst),
+			slots:   make(chan struct{}, l.config.Concurrency),
+		}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// Acquire waits for a free concurrency slot and a rate token for host.
+// The returned function must be called to free the slot.
+func (l *RateLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	h := l.getHost(host)
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	err := h.limiter.Wait(ctx)
+	if err != nil {
+		<-h.slots
+		return nil, err
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() { <-h.slots })
+	}
+	return release, nil
+}
+
+// RateLimitedTransport holds the concurrency slot until the response body is closed.
+type RateLimitedTransport struct {
+	Base    http.RoundTripper
+	Limiter *RateLimiter
+}
+
+func (t *RateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.Limiter.Acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
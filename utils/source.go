@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+type sourceRecorderKey struct{}
+
+// SourceRecorder collects the upstream origins that answered the requests
+// made with its context, so handlers can report which mirror was used.
+type SourceRecorder struct {
+	mutex   sync.Mutex
+	sources []string
+}
+
+func WithSourceRecorder(ctx context.Context) (context.Context, *SourceRecorder) {
+	recorder := &SourceRecorder{}
+	return context.WithValue(ctx, sourceRecorderKey{}, recorder), recorder
+}
+
+func recordSource(ctx context.Context, rawURL string) {
+	recorder, ok := ctx.Value(sourceRecorderKey{}).(*SourceRecorder)
+	if !ok {
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if !slices.Contains(recorder.sources, origin) {
+		recorder.sources = append(recorder.sources, origin)
+	}
+}
+
+// Sources returns the origins in the order they were first used. It is
+// empty when everything was served from cache.
+func (recorder *SourceRecorder) Sources() []string {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return slices.Clone(recorder.sources)
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"time"
+	// the distroless image has no zoneinfo, embed it into the binary
+	_ "time/tzdata"
+)
+
+var MoscowLocation = loadLocation("Europe/Moscow", 3*60*60)
+
+func loadLocation(name string, offset int) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.FixedZone(name, offset)
+	}
+	return location
+}
+
+// TradeDate returns the calendar date of t in location as UTC midnight,
+// the same form history entries use for their dates.
+func TradeDate(t time.Time, location *time.Location) time.Time {
+	year, month, day := t.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"context"
 	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -14,10 +16,29 @@ import (
 var URLS_ALLOW_LIST []string = []string{
 	constants.MoexBaseApiURL,
 	constants.SpbexBaseApiURL,
+	constants.CbrBaseApiURL,
 }
 
-func CheckSafeURL(url string) bool {
-	for _, u := range URLS_ALLOW_LIST {
+// Fetcher gets URLs starting with one of AllowList using Client. Providers
+// hold their own Fetcher so tests can point them to a fake server.
+type Fetcher struct {
+	Client    *http.Client
+	AllowList []string
+	UserAgent string
+	// Mirrors are base URLs serving the same data, tried in order when a
+	// URL starting with one of them fails.
+	Mirrors []string
+}
+
+func NewFetcher(client *http.Client, allowList ...string) *Fetcher {
+	return &Fetcher{
+		Client:    client,
+		AllowList: allowList,
+	}
+}
+
+func (fetcher *Fetcher) CheckSafeURL(url string) bool {
+	for _, u := range fetcher.AllowList {
 		if strings.HasPrefix(url, u) {
 			return true
 		}
@@ -25,27 +46,77 @@ func CheckSafeURL(url string) bool {
 	return false
 }
 
-func HttpGet(url string) ([]byte, error) {
+// candidates returns url on every mirror, starting with the first mirror.
+func (fetcher *Fetcher) candidates(url string) []string {
+	for _, mirror := range fetcher.Mirrors {
+		path, ok := strings.CutPrefix(url, mirror)
+		if !ok {
+			continue
+		}
+		var candidates []string
+		for _, base := range fetcher.Mirrors {
+			if fetcher.CheckSafeURL(base + path) {
+				candidates = append(candidates, base+path)
+			}
+		}
+		return candidates
+	}
+	return []string{url}
+}
+
+func (fetcher *Fetcher) Get(ctx context.Context, url string) ([]byte, error) {
 
-	if !CheckSafeURL(url) {
+	if !fetcher.CheckSafeURL(url) {
 		return nil, errors.ErrorNotAllowed
 	}
 
-	resp, err := http.Get(url)
+	var err error
+	for _, candidate := range fetcher.candidates(url) {
+		var body []byte
+		var retry bool
+		body, retry, err = fetcher.get(ctx, candidate)
+		if err == nil {
+			recordSource(ctx, candidate)
+			return body, nil
+		}
+		if !retry || ctx.Err() != nil {
+			break
+		}
+		log.Printf("Could not get %s: %v\n", candidate, err)
+	}
+	return []byte{}, err
+}
 
+// get reports whether another mirror should be tried after an error.
+func (fetcher *Fetcher) get(ctx context.Context, url string) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, false, err
+	}
+	if fetcher.UserAgent != "" {
+		req.Header.Set("User-Agent", fetcher.UserAgent)
+	}
+
+	resp, err := fetcher.Client.Do(req)
+
+	if err != nil {
+		return []byte{}, true, err
 	}
 
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return []byte{}, retry, errors.ErrorCouldNotFetchData
+	}
+
 	body, err := io.ReadAll(resp.Body)
 
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, true, err
 	}
 
-	return body, nil
+	return body, false, nil
 }
 
 func StringAllowlist(s string) string {
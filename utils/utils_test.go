@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kiberdruzhinnik/go-exchange-api/errors"
+)
+
+func TestFetcherGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(r.UserAgent()))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(server.Client(), server.URL)
+
+	data, err := fetcher.Get(context.Background(), server.URL+"/up")
+	if err != nil || !strings.HasPrefix(string(data), "Go-http-client/") {
+		t.Errorf("got %q, %v, want the Go default user agent", data, err)
+	}
+
+	fetcher.UserAgent = "test-agent"
+	data, err = fetcher.Get(context.Background(), server.URL+"/up")
+	if err != nil || string(data) != "test-agent" {
+		t.Errorf("got %q, %v, want the configured user agent", data, err)
+	}
+
+	_, err = fetcher.Get(context.Background(), server.URL+"/down")
+	if err != errors.ErrorCouldNotFetchData {
+		t.Errorf("got %v for an upstream error, want %v", err, errors.ErrorCouldNotFetchData)
+	}
+
+	_, err = fetcher.Get(context.Background(), "http://example.com/")
+	if err != errors.ErrorNotAllowed {
+		t.Errorf("got %v for a URL outside of the allow list, want %v", err, errors.ErrorNotAllowed)
+	}
+}
+
+func TestClientConfigProxy(t *testing.T) {
+	config := DefaultClientConfig
+	config.Proxy = "socks5://127.0.0.1:1080"
+	if _, err := config.Transport(); err != nil {
+		t.Errorf("got %v for a socks5 proxy", err)
+	}
+
+	config.Proxy = "not a url"
+	if _, err := config.Transport(); err == nil {
+		t.Error("got no error for an invalid proxy")
+	}
+}
+
+func TestClientConfigTransport(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certificate, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the test certificate is issued for example.com
+	url := "https://example.com/"
+	for _, http2 := range []bool{true, false} {
+		config := DefaultClientConfig
+		config.AllowList = []string{url}
+		config.CAFile = caFile
+		config.Resolve = map[string]string{"example.com:443": server.Listener.Addr().String()}
+		config.HTTP2 = http2
+
+		fetcher, err := NewFetcherFromConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := fetcher.Get(context.Background(), url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := "HTTP/1.1"
+		if http2 {
+			want = "HTTP/2.0"
+		}
+		if string(data) != want {
+			t.Errorf("got %s with http2 %t, want %s", data, http2, want)
+		}
+	}
+
+	config := DefaultClientConfig
+	config.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+	if _, err := NewClient(config); err == nil {
+		t.Error("got no error for a missing CA file")
+	}
+}
+
+func TestFetcherMirrors(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	mirrored := 0
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrored++
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer mirror.Close()
+
+	fetcher := NewFetcher(http.DefaultClient, primary.URL, mirror.URL)
+	fetcher.Mirrors = []string{primary.URL, mirror.URL}
+
+	ctx, sources := WithSourceRecorder(context.Background())
+	data, err := fetcher.Get(ctx, primary.URL+"/iss")
+	if err != nil || string(data) != "/iss" {
+		t.Errorf("got %q, %v, want the mirror response", data, err)
+	}
+	if got := sources.Sources(); len(got) != 1 || got[0] != mirror.URL {
+		t.Errorf("recorded sources %v, want %s", got, mirror.URL)
+	}
+
+	// a missing page is not retried on mirrors
+	_, err = fetcher.Get(context.Background(), primary.URL+"/missing")
+	if err != errors.ErrorCouldNotFetchData || mirrored != 1 {
+		t.Errorf("got %v after %d mirror requests, want no failover", err, mirrored)
+	}
+}
+
+func TestDefaultFetcher(t *testing.T) {
+	if DefaultFetcher.Client.Timeout != DefaultClientConfig.Timeout {
+		t.Errorf("default client timeout is %v, want %v", DefaultFetcher.Client.Timeout, DefaultClientConfig.Timeout)
+	}
+	if _, ok := DefaultFetcher.Client.Transport.(*RateLimitedTransport); !ok {
+		t.Errorf("default client is not rate limited")
+	}
+}